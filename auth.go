@@ -0,0 +1,451 @@
+package commonutilities
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// authentication schemes, RFC 7235
+const (
+	AuthBasic  = "Basic"
+	AuthDigest = "Digest"
+)
+
+// digest algorithms, RFC 7616 section 3.3. An empty algorithm means MD5.
+const (
+	DigestMD5        = "MD5"
+	DigestMD5Sess    = "MD5-sess"
+	DigestSHA256     = "SHA-256"
+	DigestSHA256Sess = "SHA-256-sess"
+)
+
+// AuthParam is one name=value pair of a challenge or credentials.
+type AuthParam struct {
+	Name  string
+	Value string
+}
+
+// Challenge is a parsed WWW-Authenticate header sent by a server.
+type Challenge struct {
+	// Scheme is AuthBasic, AuthDigest or another scheme as sent
+	Scheme    string
+	Realm     string
+	Nonce     string
+	Opaque    string
+	Algorithm string
+	// QOP lists the offered qop-options, e.g. auth
+	QOP   []string
+	Stale bool
+	// Extensions keeps parameters this package does not know about
+	Extensions []AuthParam
+}
+
+// Authorization is a parsed Authorization header sent by a client.
+type Authorization struct {
+	Scheme   string
+	Username string
+	// Password is only sent with Basic
+	Password  string
+	Realm     string
+	Nonce     string
+	URI       string
+	Response  string
+	Algorithm string
+	Opaque    string
+	QOP       string
+	// NC is the nonce count as 8 hexadecimal digits, e.g. 00000001
+	NC     string
+	CNonce string
+	// Extensions keeps parameters this package does not know about
+	Extensions []AuthParam
+}
+
+// stop at the end of an authentication scheme
+var sAuthSchemeStopConditions = MaskOf(", \t\r\n")
+
+// stop at the end of an auth-param name
+var sAuthParamNameStopConditions = MaskOf("=, \t\r\n")
+
+// ParseChallenge parses the value of a WWW-Authenticate header holding one
+// challenge, e.g. `Digest realm="IP Camera", nonce="a8a2c4", stale="FALSE"`.
+func ParseChallenge(value string) (*Challenge, error) {
+	challenges, err := ParseChallenges(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(challenges) != 1 {
+		return nil, fmt.Errorf("%d challenges in %q, use ParseChallenges", len(challenges), value)
+	}
+	return challenges[0], nil
+}
+
+// ParseChallenges parses a WWW-Authenticate header value holding one or
+// more comma separated challenges, RFC 7235 section 4.1, e.g.
+// `Digest realm="a", nonce="n", Basic realm="b"`.
+func ParseChallenges(value string) ([]*Challenge, error) {
+	var challenges []*Challenge
+	parser := New(strings.TrimSpace(value))
+	for {
+		scheme, params, err := parseAuthParams(parser, value)
+		if err != nil {
+			return nil, err
+		}
+		challenge, err := newChallenge(scheme, params, value)
+		if err != nil {
+			return nil, err
+		}
+		challenges = append(challenges, challenge)
+		if parser.ParserIsEmpty() {
+			return challenges, nil
+		}
+	}
+}
+
+// newChallenge builds a Challenge from the parameters of one challenge in value.
+func newChallenge(scheme string, params []AuthParam, value string) (*Challenge, error) {
+	c := &Challenge{Scheme: scheme}
+	for _, param := range params {
+		switch strings.ToLower(param.Name) {
+		case "realm":
+			c.Realm = param.Value
+		case "nonce":
+			c.Nonce = param.Value
+		case "opaque":
+			c.Opaque = param.Value
+		case "algorithm":
+			c.Algorithm = param.Value
+		case "qop":
+			for _, qop := range strings.Split(param.Value, ",") {
+				if qop = strings.TrimSpace(qop); qop != "" {
+					c.QOP = append(c.QOP, qop)
+				}
+			}
+		case "stale":
+			c.Stale = strings.EqualFold(param.Value, "true")
+		default:
+			c.Extensions = append(c.Extensions, param)
+		}
+	}
+	if c.Scheme == AuthDigest && c.Nonce == "" {
+		return nil, fmt.Errorf("digest challenge without nonce %q", value)
+	}
+	return c, nil
+}
+
+// ParseAuthorization parses the value of an Authorization header. Basic
+// credentials are decoded into Username and Password.
+func ParseAuthorization(value string) (*Authorization, error) {
+	parser := New(strings.TrimSpace(value))
+	if strings.EqualFold(parser.ConsumeUntilWhitespace(), AuthBasic) {
+		parser.ConsumeWhitespace()
+		token := parser.ConsumeUntilWhitespace()
+		decoded, err := base64.StdEncoding.DecodeString(token)
+		if err != nil || !parser.ParserIsEmpty() {
+			return nil, fmt.Errorf("malformed basic credentials %q", value)
+		}
+		userPass := New(string(decoded))
+		a := &Authorization{Scheme: AuthBasic}
+		var ok bool
+		if a.Username, ok = userPass.GetThru(':'); !ok {
+			return nil, fmt.Errorf("malformed basic credentials %q", value)
+		}
+		a.Password = userPass.ConsumeLength(userPass.GetDataRemaining())
+		return a, nil
+	}
+
+	parser = New(strings.TrimSpace(value))
+	scheme, params, err := parseAuthParams(parser, value)
+	if err != nil {
+		return nil, err
+	}
+	if !parser.ParserIsEmpty() {
+		return nil, fmt.Errorf("malformed auth-param in %q", value)
+	}
+	a := &Authorization{Scheme: scheme}
+	for _, param := range params {
+		switch strings.ToLower(param.Name) {
+		case "username":
+			a.Username = param.Value
+		case "realm":
+			a.Realm = param.Value
+		case "nonce":
+			a.Nonce = param.Value
+		case "uri":
+			a.URI = param.Value
+		case "response":
+			a.Response = param.Value
+		case "algorithm":
+			a.Algorithm = param.Value
+		case "opaque":
+			a.Opaque = param.Value
+		case "qop":
+			a.QOP = param.Value
+		case "nc":
+			a.NC = param.Value
+		case "cnonce":
+			a.CNonce = param.Value
+		default:
+			a.Extensions = append(a.Extensions, param)
+		}
+	}
+	return a, nil
+}
+
+// parseAuthParams reads `scheme name=value, name="quoted, value"` into the
+// scheme and its parameters. Quoted values are unquoted and unescaped. It
+// stops before a token that is not followed by '=', the scheme of the next
+// challenge in the list.
+func parseAuthParams(parser *StringParser, value string) (scheme string, params []AuthParam, err error) {
+	switch scheme = parser.ConsumeUntil(sAuthSchemeStopConditions); {
+	case scheme == "":
+		return "", nil, fmt.Errorf("missing authentication scheme")
+	case strings.EqualFold(scheme, AuthBasic):
+		scheme = AuthBasic
+	case strings.EqualFold(scheme, AuthDigest):
+		scheme = AuthDigest
+	}
+
+	for {
+		parser.ConsumeWhitespace()
+		if parser.ParserIsEmpty() {
+			return scheme, params, nil
+		}
+		if parser.Expect(',') {
+			continue
+		}
+		nameMark := parser.Mark()
+		name := parser.ConsumeUntil(sAuthParamNameStopConditions)
+		parser.ConsumeWhitespace()
+		if name == "" {
+			return "", nil, fmt.Errorf("malformed auth-param in %q", value)
+		}
+		if !parser.Expect('=') {
+			parser.Reset(nameMark)
+			return scheme, params, nil
+		}
+		parser.ConsumeWhitespace()
+		var param string
+		if parser.PeekFast() == '"' {
+			var err error
+			if param, err = parser.ConsumeQuotedString(); err != nil {
+				return "", nil, fmt.Errorf("unterminated quoted-string in %q", value)
+			}
+		} else {
+			param = strings.TrimSpace(parser.ConsumeUntilStop(','))
+		}
+		params = append(params, AuthParam{Name: name, Value: param})
+	}
+}
+
+// quoteString formats inString as a quoted-string.
+func quoteString(inString string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(inString) + `"`
+}
+
+// String formats the WWW-Authenticate header value.
+func (c *Challenge) String() string {
+	var params []string
+	if c.Realm != "" || c.Scheme == AuthBasic {
+		params = append(params, "realm="+quoteString(c.Realm))
+	}
+	if c.Nonce != "" {
+		params = append(params, "nonce="+quoteString(c.Nonce))
+	}
+	if c.Opaque != "" {
+		params = append(params, "opaque="+quoteString(c.Opaque))
+	}
+	if c.Algorithm != "" {
+		params = append(params, "algorithm="+c.Algorithm)
+	}
+	if len(c.QOP) > 0 {
+		params = append(params, "qop="+quoteString(strings.Join(c.QOP, ",")))
+	}
+	if c.Stale {
+		params = append(params, "stale=TRUE")
+	}
+	for _, param := range c.Extensions {
+		params = append(params, param.Name+"="+quoteString(param.Value))
+	}
+	return c.Scheme + " " + strings.Join(params, ", ")
+}
+
+// String formats the Authorization header value.
+func (a *Authorization) String() string {
+	if a.Scheme == AuthBasic {
+		return AuthBasic + " " + base64.StdEncoding.EncodeToString([]byte(a.Username+":"+a.Password))
+	}
+	params := []string{
+		"username=" + quoteString(a.Username),
+		"realm=" + quoteString(a.Realm),
+		"nonce=" + quoteString(a.Nonce),
+		"uri=" + quoteString(a.URI),
+		"response=" + quoteString(a.Response),
+	}
+	if a.Algorithm != "" {
+		params = append(params, "algorithm="+a.Algorithm)
+	}
+	if a.Opaque != "" {
+		params = append(params, "opaque="+quoteString(a.Opaque))
+	}
+	if a.QOP != "" {
+		params = append(params, "qop="+a.QOP, "nc="+a.NC)
+	}
+	if a.CNonce != "" {
+		params = append(params, "cnonce="+quoteString(a.CNonce))
+	}
+	for _, param := range a.Extensions {
+		params = append(params, param.Name+"="+quoteString(param.Value))
+	}
+	return a.Scheme + " " + strings.Join(params, ", ")
+}
+
+// NewNonce returns 128 random bits as 32 hexadecimal digits, for use as a
+// server nonce or a client cnonce.
+func NewNonce() (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(nonce), nil
+}
+
+// NewBasicChallenge returns a Basic challenge for realm.
+func NewBasicChallenge(realm string) *Challenge {
+	return &Challenge{Scheme: AuthBasic, Realm: realm}
+}
+
+// NewDigestChallenge returns a Digest challenge for realm with a fresh
+// nonce. Set Algorithm and QOP before sending it to ask for more than the
+// RFC 2069 compatible MD5 response most cameras expect.
+func NewDigestChallenge(realm string) (*Challenge, error) {
+	nonce, err := NewNonce()
+	if err != nil {
+		return nil, err
+	}
+	return &Challenge{Scheme: AuthDigest, Realm: realm, Nonce: nonce}, nil
+}
+
+// Authorize answers the challenge for a request. For Digest with qop=auth
+// offered, a new cnonce is generated and the nonce count is 00000001.
+func (c *Challenge) Authorize(method, uri, username, password string) (*Authorization, error) {
+	switch c.Scheme {
+	case AuthBasic:
+		return &Authorization{Scheme: AuthBasic, Username: username, Password: password}, nil
+	case AuthDigest:
+	default:
+		return nil, fmt.Errorf("unsupported authentication scheme %q", c.Scheme)
+	}
+
+	a := &Authorization{
+		Scheme:    AuthDigest,
+		Username:  username,
+		Realm:     c.Realm,
+		Nonce:     c.Nonce,
+		URI:       uri,
+		Algorithm: c.Algorithm,
+		Opaque:    c.Opaque,
+	}
+	for _, qop := range c.QOP {
+		if strings.EqualFold(qop, "auth") {
+			a.QOP, a.NC = "auth", "00000001"
+		}
+	}
+	if a.QOP == "" && len(c.QOP) > 0 {
+		return nil, fmt.Errorf("unsupported qop %q", strings.Join(c.QOP, ","))
+	}
+	var err error
+	if a.QOP != "" || strings.HasSuffix(strings.ToLower(a.Algorithm), "-sess") {
+		if a.CNonce, err = NewNonce(); err != nil {
+			return nil, err
+		}
+	}
+	if a.Response, err = a.DigestResponse(method, password); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Verify checks credentials sent in answer to the challenge against the
+// password of a.Username. uri is the URI of the request as received, which
+// the digest must have been computed for. A Digest answer must return the
+// challenge's opaque unchanged and use one of the qop values it offered.
+// The caller looks up the password and checks the nonce count if it tracks
+// one.
+func (c *Challenge) Verify(a *Authorization, method, uri, password string) bool {
+	if a.Scheme != c.Scheme {
+		return false
+	}
+	switch c.Scheme {
+	case AuthBasic:
+		return subtle.ConstantTimeCompare([]byte(a.Password), []byte(password)) == 1
+	case AuthDigest:
+		if a.Realm != c.Realm || a.Nonce != c.Nonce || a.URI != uri || a.Opaque != c.Opaque ||
+			!strings.EqualFold(digestAlgorithm(a.Algorithm), digestAlgorithm(c.Algorithm)) {
+			return false
+		}
+		if len(c.QOP) > 0 && !c.offersQOP(a.QOP) {
+			// an RFC 2069 answer to a challenge that asked for qop
+			return false
+		}
+		response, err := a.DigestResponse(method, password)
+		return err == nil && subtle.ConstantTimeCompare([]byte(strings.ToLower(a.Response)), []byte(response)) == 1
+	}
+	return false
+}
+
+// offersQOP reports whether qop is one of the challenge's qop values.
+func (c *Challenge) offersQOP(qop string) bool {
+	for _, offered := range c.QOP {
+		if qop != "" && strings.EqualFold(offered, qop) {
+			return true
+		}
+	}
+	return false
+}
+
+func digestAlgorithm(algorithm string) string {
+	if algorithm == "" {
+		return DigestMD5
+	}
+	return algorithm
+}
+
+// DigestResponse computes the request-digest of RFC 2617 section 3.2.2.1
+// and RFC 7616 section 3.4.1 from the fields of a.
+func (a *Authorization) DigestResponse(method, password string) (string, error) {
+	algorithm := strings.ToLower(a.Algorithm)
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(algorithm, "-sess") {
+	case "", "md5":
+		newHash = md5.New
+	case "sha-256":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("unsupported digest algorithm %q", a.Algorithm)
+	}
+	digest := func(parts ...string) string {
+		h := newHash()
+		h.Write([]byte(strings.Join(parts, ":")))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	ha1 := digest(a.Username, a.Realm, password)
+	if strings.HasSuffix(algorithm, "-sess") {
+		ha1 = digest(ha1, a.Nonce, a.CNonce)
+	}
+	ha2 := digest(method, a.URI)
+	switch a.QOP {
+	case "":
+		return digest(ha1, a.Nonce, ha2), nil
+	case "auth":
+		return digest(ha1, a.Nonce, a.NC, a.CNonce, a.QOP, ha2), nil
+	}
+	return "", fmt.Errorf("unsupported qop %q", a.QOP)
+}
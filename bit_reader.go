@@ -0,0 +1,113 @@
+package commonutilities
+
+import (
+	"io"
+)
+
+// BitReader reads a bit stream most significant bit first, as H.264 and
+// H.265 syntax elements are coded. Errors are sticky: once the data runs out
+// every read returns zero and Err reports io.ErrUnexpectedEOF, so a whole
+// syntax structure can be read before checking for errors.
+type BitReader struct {
+	data   []byte
+	offset int // in bits
+	err    error
+}
+
+func NewBitReader(data []byte) *BitReader {
+	return &BitReader{data: data}
+}
+
+// Err returns the first error met while reading.
+func (r *BitReader) Err() error { return r.err }
+
+// BitsLeft returns the number of unread bits.
+func (r *BitReader) BitsLeft() int { return len(r.data)*8 - r.offset }
+
+// ReadBits reads n <= 64 bits as an unsigned number, u(n).
+func (r *BitReader) ReadBits(n int) (value uint64) {
+	if r.err != nil {
+		return 0
+	}
+	if n > 64 || n > r.BitsLeft() {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	for i := 0; i < n; i++ {
+		bit := r.data[r.offset>>3] >> (7 - uint(r.offset&7)) & 1
+		value = value<<1 | uint64(bit)
+		r.offset++
+	}
+	return value
+}
+
+// ReadBit reads one bit.
+func (r *BitReader) ReadBit() uint32 { return uint32(r.ReadBits(1)) }
+
+// ReadFlag reads one bit as a bool.
+func (r *BitReader) ReadFlag() bool { return r.ReadBits(1) == 1 }
+
+// ReadUint8 reads u(8).
+func (r *BitReader) ReadUint8() uint8 { return uint8(r.ReadBits(8)) }
+
+// ReadUint16 reads u(16).
+func (r *BitReader) ReadUint16() uint16 { return uint16(r.ReadBits(16)) }
+
+// ReadUint32 reads u(32).
+func (r *BitReader) ReadUint32() uint32 { return uint32(r.ReadBits(32)) }
+
+// SkipBits skips n bits.
+func (r *BitReader) SkipBits(n int) {
+	if r.err != nil {
+		return
+	}
+	if n > r.BitsLeft() {
+		r.err = io.ErrUnexpectedEOF
+		return
+	}
+	r.offset += n
+}
+
+// ReadUE reads an unsigned Exp-Golomb number, ue(v).
+func (r *BitReader) ReadUE() uint32 {
+	leadingZeroBits := 0
+	for r.err == nil && r.ReadBits(1) == 0 {
+		if leadingZeroBits++; leadingZeroBits > 31 {
+			r.err = io.ErrUnexpectedEOF
+		}
+	}
+	if r.err != nil {
+		return 0
+	}
+	return uint32(1<<uint(leadingZeroBits) - 1 + r.ReadBits(leadingZeroBits))
+}
+
+// ReadSE reads a signed Exp-Golomb number, se(v).
+func (r *BitReader) ReadSE() int32 {
+	codeNum := int64(r.ReadUE())
+	if codeNum&1 == 1 {
+		return int32((codeNum + 1) / 2)
+	}
+	return int32(-codeNum / 2)
+}
+
+// RemoveEmulationPrevention converts a NAL unit to its raw byte sequence
+// payload by dropping the emulation_prevention_three_byte of every
+// 0x000003 sequence. The input is not modified.
+func RemoveEmulationPrevention(nalUnit []byte) []byte {
+	rbsp := make([]byte, 0, len(nalUnit))
+	zeros := 0
+	for _, b := range nalUnit {
+		if zeros >= 2 && b == 3 {
+			zeros = 0
+			continue
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		rbsp = append(rbsp, b)
+	}
+	return rbsp
+}
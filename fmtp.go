@@ -0,0 +1,216 @@
+package commonutilities
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// FmtpParam is one name=value pair of an fmtp attribute.
+type FmtpParam struct {
+	Name  string
+	Value string
+}
+
+// Fmtp is a parsed "a=fmtp:" attribute value, e.g.
+// "96 packetization-mode=1; sprop-parameter-sets=Z0IAH52oFAFum4CAgIE=,aM48gA==; profile-level-id=42001F".
+type Fmtp struct {
+	PayloadType int
+	// Params are kept in the order they were sent
+	Params []FmtpParam
+}
+
+// H264ProfileLevel is a decoded profile-level-id, RFC 6184 section 8.1.
+type H264ProfileLevel struct {
+	ProfileIDC byte
+	// ConstraintFlags holds constraint_set0_flag in its most significant bit
+	ConstraintFlags byte
+	LevelIDC        byte
+}
+
+// H.264 and H.265 nal_unit_type values of parameter sets
+const (
+	h264NALTypeSPS = 7
+	h264NALTypePPS = 8
+	h265NALTypeSPS = 33
+)
+
+// ParseFmtp parses the value of an fmtp attribute, the payload type
+// followed by ';' separated parameters.
+func ParseFmtp(value string) (*Fmtp, error) {
+	parser := New(value)
+	digits, payloadType := parser.ConsumeInteger()
+	if digits == "" || (!parser.ParserIsEmpty() && parser.PeekFast() != ' ' && parser.PeekFast() != '\t') {
+		return nil, fmt.Errorf("malformed fmtp %q", value)
+	}
+	parser.ConsumeWhitespace()
+	fmtp, err := ParseFmtpParams(parser.ConsumeLength(parser.GetDataRemaining()))
+	if err != nil {
+		return nil, err
+	}
+	fmtp.PayloadType = int(payloadType)
+	return fmtp, nil
+}
+
+// stop at the end of an fmtp parameter name
+var sFmtpNameStopConditions = MaskOf("=;")
+
+// ParseFmtpParams parses the parameters of an fmtp attribute without the
+// payload type, as returned by MediaDescription.Fmtp. A value may be a
+// quoted-string, which can hold a ';'.
+func ParseFmtpParams(params string) (*Fmtp, error) {
+	fmtp := &Fmtp{}
+	parser := New(params)
+	for {
+		parser.ConsumeWhitespace()
+		if parser.ParserIsEmpty() {
+			return fmtp, nil
+		}
+		if parser.Expect(';') {
+			continue
+		}
+		name := strings.TrimSpace(parser.ConsumeUntil(sFmtpNameStopConditions))
+		if name == "" {
+			return nil, fmt.Errorf("malformed fmtp parameters %q", params)
+		}
+		var value string
+		if parser.Expect('=') {
+			parser.ConsumeWhitespace()
+			if parser.PeekFast() == '"' {
+				var err error
+				if value, err = parser.ConsumeQuotedString(); err != nil {
+					return nil, fmt.Errorf("fmtp parameter %s: %w", name, err)
+				}
+				parser.ConsumeWhitespace()
+				if !parser.ParserIsEmpty() && !parser.Expect(';') {
+					return nil, fmt.Errorf("malformed fmtp parameters %q", params)
+				}
+			} else {
+				value = strings.TrimSpace(parser.ConsumeUntilStop(';'))
+			}
+		}
+		fmtp.Params = append(fmtp.Params, FmtpParam{Name: name, Value: value})
+	}
+}
+
+// ParsedFmtp parses the a=fmtp attribute for payloadType.
+func (m *MediaDescription) ParsedFmtp(payloadType int) (*Fmtp, error) {
+	params, ok := m.Fmtp(payloadType)
+	if !ok {
+		return nil, fmt.Errorf("no fmtp for payload type %d", payloadType)
+	}
+	fmtp, err := ParseFmtpParams(params)
+	if err != nil {
+		return nil, err
+	}
+	fmtp.PayloadType = payloadType
+	return fmtp, nil
+}
+
+// Get returns the value of the parameter called name. Names are compared
+// case-insensitively.
+func (f *Fmtp) Get(name string) (string, bool) {
+	for _, param := range f.Params {
+		if strings.EqualFold(param.Name, name) {
+			return param.Value, true
+		}
+	}
+	return "", false
+}
+
+// Map returns the parameters keyed by lower case name.
+func (f *Fmtp) Map() map[string]string {
+	params := make(map[string]string, len(f.Params))
+	for _, param := range f.Params {
+		params[strings.ToLower(param.Name)] = param.Value
+	}
+	return params
+}
+
+// NALUnits decodes a parameter holding a comma separated list of base64
+// NAL units, such as sprop-parameter-sets or sprop-sps.
+func (f *Fmtp) NALUnits(name string) ([][]byte, error) {
+	value, ok := f.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("fmtp parameter %s not found", name)
+	}
+	var nalUnits [][]byte
+	parser := New(value)
+	for !parser.ParserIsEmpty() {
+		encoded, _ := parser.GetThru(',')
+		if encoded = strings.TrimSpace(encoded); encoded == "" {
+			continue
+		}
+		nalUnit, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			// some cameras leave out the padding
+			if nalUnit, err = base64.RawStdEncoding.DecodeString(encoded); err != nil {
+				return nil, fmt.Errorf("malformed %s %q: %v", name, encoded, err)
+			}
+		}
+		if len(nalUnit) == 0 {
+			return nil, fmt.Errorf("empty NAL unit in %s", name)
+		}
+		nalUnits = append(nalUnits, nalUnit)
+	}
+	return nalUnits, nil
+}
+
+// H264ParameterSets decodes sprop-parameter-sets into SPS and PPS NAL units.
+func (f *Fmtp) H264ParameterSets() (sps, pps [][]byte, err error) {
+	nalUnits, err := f.NALUnits("sprop-parameter-sets")
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, nalUnit := range nalUnits {
+		switch nalUnit[0] & 0x1f {
+		case h264NALTypeSPS:
+			sps = append(sps, nalUnit)
+		case h264NALTypePPS:
+			pps = append(pps, nalUnit)
+		}
+	}
+	return sps, pps, nil
+}
+
+// H265ParameterSets decodes sprop-vps, sprop-sps and sprop-pps, RFC 7798
+// section 7.1. Missing parameters yield no NAL units.
+func (f *Fmtp) H265ParameterSets() (vps, sps, pps [][]byte, err error) {
+	sets := []*[][]byte{&vps, &sps, &pps}
+	for i, name := range []string{"sprop-vps", "sprop-sps", "sprop-pps"} {
+		if _, ok := f.Get(name); !ok {
+			continue
+		}
+		if *sets[i], err = f.NALUnits(name); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return vps, sps, pps, nil
+}
+
+// H264ProfileLevel decodes the hexadecimal profile-level-id.
+func (f *Fmtp) H264ProfileLevel() (H264ProfileLevel, error) {
+	value, ok := f.Get("profile-level-id")
+	if !ok {
+		return H264ProfileLevel{}, fmt.Errorf("fmtp parameter profile-level-id not found")
+	}
+	decoded, err := hex.DecodeString(value)
+	if err != nil || len(decoded) != 3 {
+		return H264ProfileLevel{}, fmt.Errorf("malformed profile-level-id %q", value)
+	}
+	return H264ProfileLevel{ProfileIDC: decoded[0], ConstraintFlags: decoded[1], LevelIDC: decoded[2]}, nil
+}
+
+// Level returns the level as written in the specification, e.g. 3.1 for
+// level_idc 31 or 1b for level 11 with constraint_set3_flag in the
+// Baseline, Main and Extended profiles.
+func (p H264ProfileLevel) Level() string {
+	if p.LevelIDC == 11 && p.ConstraintFlags&0x10 != 0 && (p.ProfileIDC == 66 || p.ProfileIDC == 77 || p.ProfileIDC == 88) {
+		return "1b"
+	}
+	if p.LevelIDC%10 == 0 {
+		return fmt.Sprintf("%d", p.LevelIDC/10)
+	}
+	return fmt.Sprintf("%d.%d", p.LevelIDC/10, p.LevelIDC%10)
+}
@@ -0,0 +1,175 @@
+package commonutilities
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrNeedMoreData is returned when a message ends before its header block or
+// body is complete. Read more from the connection and parse again.
+var ErrNeedMoreData = errors.New("need more data")
+
+// HeaderField is one "Name: value" line of a header block.
+type HeaderField struct {
+	Name  string
+	Value string
+}
+
+// Header is an ordered multimap of header fields, kept in the order they
+// were received. Names are compared case-insensitively.
+type Header []HeaderField
+
+// Get returns the value of the first field called name, or "".
+func (h Header) Get(name string) string {
+	for _, field := range h {
+		if strings.EqualFold(field.Name, name) {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// Values returns the values of every field called name in order.
+func (h Header) Values(name string) (values []string) {
+	for _, field := range h {
+		if strings.EqualFold(field.Name, name) {
+			values = append(values, field.Value)
+		}
+	}
+	return
+}
+
+// Add appends a field.
+func (h *Header) Add(name, value string) {
+	*h = append(*h, HeaderField{Name: name, Value: value})
+}
+
+// Set replaces the first field called name and removes the others, or
+// appends the field if there is none.
+func (h *Header) Set(name, value string) {
+	for i, field := range *h {
+		if strings.EqualFold(field.Name, name) {
+			tail := (*h)[i+1:].without(name)
+			(*h)[i].Value = value
+			*h = (*h)[:i+1+len(tail)]
+			return
+		}
+	}
+	h.Add(name, value)
+}
+
+// Del removes every field called name.
+func (h *Header) Del(name string) {
+	*h = h.without(name)
+}
+
+func (h Header) without(name string) Header {
+	kept := h[:0]
+	for _, field := range h {
+		if !strings.EqualFold(field.Name, name) {
+			kept = append(kept, field)
+		}
+	}
+	return kept
+}
+
+// Float returns the value of the first field called name as a number, for
+// headers such as Scale and Speed, e.g. "Scale: -0.5".
+func (h Header) Float(name string) (float64, error) {
+	value := h.Get(name)
+	if value == "" {
+		return 0, fmt.Errorf("header %s not found", name)
+	}
+	parser := New(value)
+	number, err := parser.ConsumeFloatErr()
+	if err == nil && !parser.ParserIsEmpty() {
+		err = parser.NewParseError("end of number")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("malformed %s %q: %w", name, value, err)
+	}
+	return number, nil
+}
+
+// stop at the end of a header name
+var sHeaderNameMask = MaskOf(":\r\n")
+
+// parseHeader reads "Name: value" lines up to and including the empty line
+// that ends a header block. Continuation lines starting with a space or a tab
+// are folded into the previous value with a single space.
+func parseHeader(parser *StringParser) (Header, error) {
+	var header Header
+	for parser.PeekFast() != '\r' && parser.PeekFast() != '\n' {
+		if parser.ParserIsEmpty() {
+			return nil, ErrNeedMoreData
+		}
+		lineMark := parser.Mark()
+		name := strings.TrimSpace(parser.ConsumeUntil(sHeaderNameMask))
+		if parser.ParserIsEmpty() {
+			return nil, ErrNeedMoreData
+		}
+		if !parser.Expect(':') || name == "" {
+			parser.Reset(lineMark)
+			return nil, parser.NewParseError("\"Name: value\" header")
+		}
+		parser.ConsumeUntil(sLinearWhitespaceMask)
+		valueMark := parser.Mark()
+		value := strings.TrimSpace(parser.ConsumeUntil(sEOLMask))
+		if !parser.ExpectEOL() {
+			return nil, ErrNeedMoreData
+		}
+		for parser.PeekFast() == ' ' || parser.PeekFast() == '\t' {
+			if folded := strings.TrimSpace(parser.ConsumeUntil(sEOLMask)); folded != "" {
+				value = strings.TrimSpace(value + " " + folded)
+			}
+			if !parser.ExpectEOL() {
+				return nil, ErrNeedMoreData
+			}
+		}
+		if maxDigits, ok := sNumericHeaderDigits[strings.ToLower(name)]; ok {
+			if _, err := headerNumber(value, maxDigits); err != nil {
+				parser.Reset(valueMark)
+				parseError := parser.NewParseError(name + " digits")
+				if errors.Is(err, strconv.ErrRange) {
+					parseError.Err = fmt.Errorf("%s longer than %d digits: %w", name, maxDigits, strconv.ErrRange)
+				}
+				return nil, parseError
+			}
+		}
+		header.Add(name, value)
+	}
+	parser.ExpectEOL()
+	return header, nil
+}
+
+// headers whose value must be a decimal number, with the most digits they
+// may have: RFC 7826 limits CSeq to 9 digits, and 18 digits keep any
+// Content-Length within an int
+var sNumericHeaderDigits = map[string]int{"cseq": 9, "content-length": 18}
+
+func headerNumber(value string, maxDigits int) (uint64, error) {
+	parser := New(value)
+	parser.SetMaxDigits(maxDigits)
+	number, err := parser.ConsumeUint64()
+	if err == nil && !parser.ParserIsEmpty() {
+		err = parser.NewParseError("end of number")
+	}
+	return number, err
+}
+
+// parseBody slices the body announced by the Content-Length header out of
+// whatever follows the header block. parseHeader has already checked that
+// Content-Length is a number.
+func parseBody(parser *StringParser, header Header) (string, error) {
+	contentLength := header.Get("Content-Length")
+	if contentLength == "" {
+		return "", nil
+	}
+	length, _ := headerNumber(contentLength, sNumericHeaderDigits["content-length"])
+	if uint64(parser.GetDataRemaining()) < length {
+		return "", ErrNeedMoreData
+	}
+	return parser.ConsumeLength(int(length)), nil
+}
@@ -0,0 +1,313 @@
+package commonutilities
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The struct tag UnmarshalHeader and MarshalHeader read, e.g.
+//
+//	type PlayRequest struct {
+//		CSeq      int         `rtsp:"CSeq"`
+//		Session   Session     `rtsp:"Session"`
+//		Range     *Range      `rtsp:"Range"`
+//		Scale     float64     `rtsp:"Scale,omitempty"`
+//		Transport []Transport `rtsp:"Transport"`
+//		Require   []string    `rtsp:"Require"`
+//	}
+//
+// Fields without the tag, or tagged "-", are left alone.
+const headerTagName = "rtsp"
+
+// UnmarshalHeader copies the fields of header into the tagged fields of the
+// struct v points to. Names are compared case-insensitively and a field
+// whose header is missing keeps its value. Values are converted to:
+//
+//   - string, any integer or float type
+//   - time.Duration, from seconds such as "60" or "0.5"
+//   - Transport, Range, Session, Challenge and Authorization, parsed by
+//     their Parse* function; []Transport, []RTPInfo and []Challenge
+//     collect the elements of every header with the name
+//   - a pointer to one of these, allocated only if the header is present
+//   - a slice of one of these, one element per header with the name, e.g.
+//     []string for Require
+//
+// A non-slice field takes the first header with the name.
+func UnmarshalHeader(header Header, v any) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("UnmarshalHeader needs a pointer to a struct, not %T", v)
+	}
+	target = target.Elem()
+	for i := 0; i < target.NumField(); i++ {
+		name, _, ok := headerTag(target.Type().Field(i))
+		if !ok {
+			continue
+		}
+		values := header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		if err := unmarshalField(target.Field(i), values); err != nil {
+			return fmt.Errorf("header %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// MarshalHeader builds a header block from the tagged fields of v, a struct
+// or a pointer to one. CSeq comes first, as many clients expect, and the
+// other fields follow in the order they are declared. Nil pointers and empty
+// slices are skipped, as are zero values of fields tagged omitempty. A slice
+// other than []Transport, []RTPInfo and []Challenge adds one header per
+// element.
+func MarshalHeader(v any) (Header, error) {
+	source := reflect.ValueOf(v)
+	if source.Kind() == reflect.Pointer && !source.IsNil() {
+		source = source.Elem()
+	}
+	if source.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("MarshalHeader needs a struct, not %T", v)
+	}
+	if !source.CanAddr() {
+		addressable := reflect.New(source.Type()).Elem()
+		addressable.Set(source)
+		source = addressable
+	}
+
+	var header, cseq Header
+	for i := 0; i < source.NumField(); i++ {
+		name, omitEmpty, ok := headerTag(source.Type().Field(i))
+		field := source.Field(i)
+		if !ok || (omitEmpty && field.IsZero()) {
+			continue
+		}
+		values, err := marshalField(field)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %w", name, err)
+		}
+		for _, value := range values {
+			if strings.EqualFold(name, "CSeq") {
+				cseq.Add(name, value)
+			} else {
+				header.Add(name, value)
+			}
+		}
+	}
+	return append(cseq, header...), nil
+}
+
+// String formats the header block, each field on a "Name: value" line and
+// the block ending with an empty line.
+func (h Header) String() string {
+	var block strings.Builder
+	for _, field := range h {
+		block.WriteString(field.Name)
+		block.WriteString(": ")
+		block.WriteString(field.Value)
+		block.WriteString("\r\n")
+	}
+	block.WriteString("\r\n")
+	return block.String()
+}
+
+// headerTag returns the header name and the omitempty option of a field.
+func headerTag(field reflect.StructField) (name string, omitEmpty bool, ok bool) {
+	tag, ok := field.Tag.Lookup(headerTagName)
+	if !ok || tag == "-" || !field.IsExported() {
+		return "", false, false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, options == "omitempty", true
+}
+
+// isHeaderList tells the slice types whose elements come from within one
+// header value from the slices that hold one header value per element.
+func isHeaderList(fieldType reflect.Type) bool {
+	return fieldType == reflect.TypeOf([]Transport(nil)) || fieldType == reflect.TypeOf([]RTPInfo(nil)) ||
+		fieldType == reflect.TypeOf([]Challenge(nil))
+}
+
+func unmarshalField(field reflect.Value, values []string) error {
+	switch {
+	case isHeaderList(field.Type()):
+		for _, value := range values {
+			if err := unmarshalValue(field, value); err != nil {
+				return err
+			}
+		}
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8:
+		elements := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := unmarshalValue(elements.Index(i), value); err != nil {
+				return err
+			}
+		}
+		field.Set(elements)
+	default:
+		return unmarshalValue(field, values[0])
+	}
+	return nil
+}
+
+// unmarshalValue converts one header value into dst.
+func unmarshalValue(dst reflect.Value, value string) (err error) {
+	if dst.Kind() == reflect.Pointer {
+		allocated := reflect.New(dst.Type().Elem())
+		if err = unmarshalValue(allocated.Elem(), value); err == nil {
+			dst.Set(allocated)
+		}
+		return err
+	}
+
+	switch target := dst.Addr().Interface().(type) {
+	case *string:
+		*target = value
+	case *[]byte:
+		*target = []byte(value)
+	case *time.Duration:
+		parser := New(strings.TrimSpace(value))
+		if *target, err = parser.ConsumeNPTDurationErr(); err == nil && !parser.ParserIsEmpty() {
+			err = parser.NewParseError("seconds")
+		}
+	case *Transport:
+		var transports []Transport
+		if transports, err = ParseTransport(value); err == nil {
+			*target = transports[0]
+		}
+	case *[]Transport:
+		var transports []Transport
+		if transports, err = ParseTransport(value); err == nil {
+			*target = append(*target, transports...)
+		}
+	case *[]RTPInfo:
+		var infos []RTPInfo
+		if infos, err = ParseRTPInfo(value); err == nil {
+			*target = append(*target, infos...)
+		}
+	case *Range:
+		*target, err = ParseRange(value)
+	case *Session:
+		*target, err = ParseSession(value)
+	case *Challenge:
+		var challenge *Challenge
+		if challenge, err = ParseChallenge(value); err == nil {
+			*target = *challenge
+		}
+	case *[]Challenge:
+		var challenges []*Challenge
+		if challenges, err = ParseChallenges(value); err == nil {
+			for _, challenge := range challenges {
+				*target = append(*target, *challenge)
+			}
+		}
+	case *Authorization:
+		var authorization *Authorization
+		if authorization, err = ParseAuthorization(value); err == nil {
+			*target = *authorization
+		}
+	default:
+		return unmarshalNumber(dst, strings.TrimSpace(value))
+	}
+	return err
+}
+
+func unmarshalNumber(dst reflect.Value, value string) error {
+	var err error
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var number int64
+		if number, err = strconv.ParseInt(value, 10, dst.Type().Bits()); err == nil {
+			dst.SetInt(number)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var number uint64
+		if number, err = strconv.ParseUint(value, 10, dst.Type().Bits()); err == nil {
+			dst.SetUint(number)
+		}
+	case reflect.Float32, reflect.Float64:
+		var number float64
+		if number, err = strconv.ParseFloat(value, dst.Type().Bits()); err == nil {
+			dst.SetFloat(number)
+		}
+	default:
+		return fmt.Errorf("unsupported field type %s", dst.Type())
+	}
+	var numError *strconv.NumError
+	if errors.As(err, &numError) {
+		return fmt.Errorf("malformed number %q: %w", value, numError.Err)
+	}
+	return err
+}
+
+func marshalField(field reflect.Value) ([]string, error) {
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 && !isHeaderList(field.Type()) {
+		values := make([]string, 0, field.Len())
+		for i := 0; i < field.Len(); i++ {
+			value, err := marshalValue(field.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+	if (field.Kind() == reflect.Pointer && field.IsNil()) || (field.Kind() == reflect.Slice && field.Len() == 0) {
+		return nil, nil
+	}
+	value, err := marshalValue(field)
+	if err != nil {
+		return nil, err
+	}
+	return []string{value}, nil
+}
+
+// marshalValue formats one header value.
+func marshalValue(src reflect.Value) (string, error) {
+	if src.Kind() == reflect.Pointer {
+		if src.IsNil() {
+			return "", fmt.Errorf("nil %s", src.Type())
+		}
+		return marshalValue(src.Elem())
+	}
+	switch src := src.Interface().(type) {
+	case string:
+		return src, nil
+	case []byte:
+		return string(src), nil
+	case time.Duration:
+		return strconv.FormatFloat(src.Seconds(), 'f', -1, 64), nil
+	case []Transport:
+		return FormatTransport(src), nil
+	case []RTPInfo:
+		return FormatRTPInfo(src), nil
+	case []Challenge:
+		challenges := make([]string, len(src))
+		for i, challenge := range src {
+			challenges[i] = challenge.String()
+		}
+		return strings.Join(challenges, ", "), nil
+	case Challenge:
+		return src.String(), nil
+	case Authorization:
+		return src.String(), nil
+	case fmt.Stringer:
+		return src.String(), nil
+	}
+	switch src.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(src.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(src.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(src.Float(), 'f', -1, src.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported field type %s", src.Type())
+}
@@ -0,0 +1,161 @@
+package commonutilities
+
+import (
+	"strings"
+)
+
+// HeaderParam is one ";name=value" parameter of a header element. Value has
+// its quotes and escapes removed.
+type HeaderParam struct {
+	Name  string
+	Value string
+	// HasValue tells "name=" from a bare "name" such as "unicast"
+	HasValue bool
+}
+
+// HeaderElement is one comma separated element of a header value: a primary
+// token followed by parameters in the order they were sent, e.g.
+// "E1155C20;timeout=60" in a Session header or
+// "RTP/AVP;unicast;client_port=8000-8001" in a Transport header.
+type HeaderElement struct {
+	Token  string
+	Params []HeaderParam
+}
+
+// HeaderValue is a comma separated list of elements, the grammar shared by
+// Transport, Session, RTP-Info, Range, Accept and many other headers. The
+// auth-param lists of WWW-Authenticate and Authorization separate their
+// parameters with ',' rather than ';' and are parsed in auth.go instead.
+type HeaderValue []HeaderElement
+
+// stop at the end of a primary token, or at a quoted-string inside it
+var sHeaderTokenStopConditions = MaskOf(";,\"")
+
+// stop at the end of a parameter name
+var sHeaderParamNameStopConditions = MaskOf("=;,")
+
+// stop at the end of a parameter value
+var sHeaderParamValueStopConditions = MaskOf(";,")
+
+// a parameter value holding one of these is written as a quoted-string
+var sHeaderParamQuoteMask = MaskOf(";,=\"\\ \t\r\n")
+
+// ParseHeaderValue parses a header value into its elements. Empty elements
+// and parameters, as in "a,,b;;c", are skipped. A parameter value may be a
+// quoted-string, which can hold ';' and ','. The error is a *ParseError.
+func ParseHeaderValue(value string) (HeaderValue, error) {
+	var elements HeaderValue
+	parser := New(value)
+	for {
+		parser.ConsumeWhitespace()
+		if parser.ParserIsEmpty() {
+			return elements, nil
+		}
+		if parser.Expect(',') {
+			continue
+		}
+		element, err := parseHeaderElement(parser)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+	}
+}
+
+// parseHeaderElement reads one element up to the ',' that ends it.
+func parseHeaderElement(parser *StringParser) (element HeaderElement, err error) {
+	start := parser.GetDataParsedLen()
+	for {
+		parser.ConsumeUntil(sHeaderTokenStopConditions)
+		if parser.PeekFast() != '"' {
+			break
+		}
+		if _, err = parser.ConsumeQuotedString(); err != nil {
+			return element, err
+		}
+	}
+	element.Token = strings.TrimSpace(parser.GetStream()[start:parser.GetDataParsedLen()])
+
+	for parser.Expect(';') {
+		parser.ConsumeWhitespace()
+		nameMark := parser.Mark()
+		param := HeaderParam{Name: strings.TrimSpace(parser.ConsumeUntil(sHeaderParamNameStopConditions))}
+		if parser.Expect('=') {
+			param.HasValue = true
+			parser.ConsumeWhitespace()
+			if parser.PeekFast() == '"' {
+				if param.Value, err = parser.ConsumeQuotedString(); err != nil {
+					return element, err
+				}
+				parser.ConsumeWhitespace()
+			} else {
+				param.Value = strings.TrimSpace(parser.ConsumeUntil(sHeaderParamValueStopConditions))
+			}
+		}
+		if param.Name == "" {
+			if param.HasValue {
+				parser.Reset(nameMark)
+				return element, parser.NewParseError("parameter name")
+			}
+			continue
+		}
+		element.Params = append(element.Params, param)
+	}
+	if !parser.ParserIsEmpty() && parser.PeekFast() != ',' {
+		return element, parser.NewParseError("';' or ','")
+	}
+	return element, nil
+}
+
+// Param returns the value of the first parameter called name. Names are
+// compared case-insensitively.
+func (e HeaderElement) Param(name string) (string, bool) {
+	for _, param := range e.Params {
+		if strings.EqualFold(param.Name, name) {
+			return param.Value, true
+		}
+	}
+	return "", false
+}
+
+// AddParam appends a "name=value" parameter.
+func (e *HeaderElement) AddParam(name, value string) {
+	e.Params = append(e.Params, HeaderParam{Name: name, Value: value, HasValue: true})
+}
+
+// AddFlag appends a parameter without a value, such as "unicast".
+func (e *HeaderElement) AddFlag(name string) {
+	e.Params = append(e.Params, HeaderParam{Name: name})
+}
+
+// String formats the parameter, quoting the value if it is empty or holds a
+// separator.
+func (p HeaderParam) String() string {
+	if !p.HasValue && p.Value == "" {
+		return p.Name
+	}
+	if p.Value == "" || strings.IndexFunc(p.Value, sHeaderParamQuoteMask.Runes()) >= 0 {
+		return p.Name + "=" + quoteString(p.Value)
+	}
+	return p.Name + "=" + p.Value
+}
+
+// String formats the element as "token;name=value;flag".
+func (e HeaderElement) String() string {
+	var formatted strings.Builder
+	formatted.WriteString(e.Token)
+	for _, param := range e.Params {
+		formatted.WriteByte(';')
+		formatted.WriteString(param.String())
+	}
+	return formatted.String()
+}
+
+// String formats the elements separated by ", ".
+func (v HeaderValue) String() string {
+	elements := make([]string, len(v))
+	for i, element := range v {
+		elements[i] = element.String()
+	}
+	return strings.Join(elements, ", ")
+}
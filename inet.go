@@ -33,8 +33,7 @@ func Ntohl(packet []byte) (uint32, error) {
 
 	err := binary.Read(buffer, binary.BigEndian, &value)
 	if err != nil {
-		fmt.Println("failed to read binary.", err.Error())
-		return value, err
+		return value, fmt.Errorf("failed to read binary: %w", err)
 	}
 
 	return value, nil
@@ -43,8 +42,7 @@ func Ntohl(packet []byte) (uint32, error) {
 func OurIPAddress() (string, error) {
 	addrs, err := net.InterfaceAddrs()
 	if err != nil {
-		fmt.Printf("Failed to get InterfaceAddrs.%s\n", err.Error())
-		return "", err
+		return "", fmt.Errorf("failed to get InterfaceAddrs: %w", err)
 	}
 
 	var ip string
@@ -1,250 +1,128 @@
 // Built-in masks for common stop conditions
 package commonutilities
 
-// built in masks for some common stop conditions
-var sNonWordMask = []uint8{
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //0-9
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //10-19
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //20-29
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //30-39
-	1, 1, 1, 1, 1, 0, 1, 1, 1, 1, //40-49 - is a word
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //50-59
-	1, 1, 1, 1, 1, 0, 0, 0, 0, 0, //60-69 //stop on every character except a letter
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //70-79
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //80-89
-	0, 1, 1, 1, 1, 0, 1, 0, 0, 0, //90-99 _ is a word
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //100-109
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //110-119
-	0, 0, 0, 1, 1, 1, 1, 1, 1, 1, //120-129
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //130-139
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //140-149
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //150-159
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //160-169
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //170-179
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //180-189
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //190-199
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //200-209
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //210-219
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //220-229
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //230-239
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //240-249
-	1, 1, 1, 1, 1, 1,             //250-255
+import (
+	"unicode"
+)
+
+// Mask is a set of bytes, one bit per possible value. ConsumeUntil stops on
+// the first byte contained in the mask.
+type Mask [4]uint64
+
+// MaskOf returns a mask containing every byte of inChars.
+func MaskOf(inChars string) (m Mask) {
+	for i := 0; i < len(inChars); i++ {
+		m[inChars[i]>>6] |= 1 << (inChars[i] & 63)
+	}
+	return
+}
+
+// MaskRange returns a mask containing the bytes first through last inclusive.
+func MaskRange(first, last byte) (m Mask) {
+	for c := int(first); c <= int(last); c++ {
+		m[c>>6] |= 1 << (c & 63)
+	}
+	return
 }
+
+// MaskFunc returns a mask containing every byte for which inFunc is true.
+func MaskFunc(inFunc func(c byte) bool) (m Mask) {
+	for c := 0; c < 256; c++ {
+		if inFunc(byte(c)) {
+			m[c>>6] |= 1 << (c & 63)
+		}
+	}
+	return
+}
+
+// Contains returns true if c is in the mask.
+func (m Mask) Contains(c byte) bool {
+	return m[c>>6]&(1<<(c&63)) != 0
+}
+
+// Union returns the bytes in either mask.
+func (m Mask) Union(other Mask) Mask {
+	return Mask{m[0] | other[0], m[1] | other[1], m[2] | other[2], m[3] | other[3]}
+}
+
+// Intersect returns the bytes in both masks.
+func (m Mask) Intersect(other Mask) Mask {
+	return Mask{m[0] & other[0], m[1] & other[1], m[2] & other[2], m[3] & other[3]}
+}
+
+// Invert returns the bytes not in the mask.
+func (m Mask) Invert() Mask {
+	return Mask{^m[0], ^m[1], ^m[2], ^m[3]}
+}
+
+// RuneMask is a set of runes for the UTF-8 aware ConsumeUntilRune. Use a
+// byte Mask for ASCII protocol tokens, it is much faster.
+type RuneMask func(r rune) bool
+
+// Runes returns a rune mask containing the ASCII characters of the mask.
+// Bytes >= 0x80 are not characters on their own in UTF-8 and are dropped.
+func (m Mask) Runes() RuneMask {
+	return func(r rune) bool { return r < 0x80 && m.Contains(byte(r)) }
+}
+
+// Contains returns true if r is in the mask.
+func (m RuneMask) Contains(r rune) bool {
+	return m(r)
+}
+
+// Union returns the runes in either mask.
+func (m RuneMask) Union(other RuneMask) RuneMask {
+	return func(r rune) bool { return m(r) || other(r) }
+}
+
+// Invert returns the runes not in the mask.
+func (m RuneMask) Invert() RuneMask {
+	return func(r rune) bool { return !m(r) }
+}
+
+// built in masks for some common stop conditions
+
+// stop on every character except a letter, - and _ are word characters
+var sNonWordMask = sWordMask.Invert()
 var NonWordMask = sNonWordMask
 
 // stop when you hit a word
-var sWordMask = []uint8{
-	// Inverse of the above
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //0-9
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //10-19
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //20-29
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //30-39
-	0, 0, 0, 0, 0, 1, 0, 0, 0, 0, //40-49 - is a word
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //50-59
-	0, 0, 0, 0, 0, 1, 1, 1, 1, 1, //60-69 //stop on every character except a letter
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //70-79
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //80-89
-	1, 0, 0, 0, 0, 1, 0, 1, 1, 1, //90-99 _ is a word
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //100-109
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //110-119
-	1, 1, 1, 0, 0, 0, 0, 0, 0, 0, //120-129
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //130-139
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //140-149
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //150-159
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //160-169
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //170-179
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //180-189
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //190-199
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //200-209
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //210-219
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //220-229
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //230-239
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //240-249
-	0, 0, 0, 0, 0, 0,             //250-255
-}
+var sWordMask = MaskRange('a', 'z').Union(MaskRange('A', 'Z')).Union(MaskOf("-_"))
 var WordMask = sWordMask
 
+// stop when you hit a word in any script, letters and combining marks of
+// every language, - and _ are word characters
+var sWordRuneMask = RuneMask(unicode.IsLetter).Union(unicode.IsMark).Union(MaskOf("-_").Runes())
+var WordRuneMask = sWordRuneMask
+
+// stop on every character except a word character of any script
+var sNonWordRuneMask = sWordRuneMask.Invert()
+var NonWordRuneMask = sNonWordRuneMask
+
 // stop when you hit a digit
-var sDigitMask = []uint8{
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //0-9
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //10-19
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //20-29
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //30-39
-	0, 0, 0, 0, 0, 0, 0, 0, 1, 1, //40-49 //stop on every character except a number
-	1, 1, 1, 1, 1, 1, 1, 1, 0, 0, //50-59
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //60-69
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //70-79
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //80-89
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //90-99
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //100-109
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //110-119
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //120-129
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //130-139
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //140-149
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //150-159
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //160-169
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //170-179
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //180-189
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //190-199
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //200-209
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //210-219
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //220-229
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //230-239
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //240-249
-	0, 0, 0, 0, 0, 0,             //250-255
-}
+var sDigitMask = MaskRange('0', '9')
 var DigitMask = sDigitMask
 
-// stop when you hit an eol
-var sEOLMask = []uint8{
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //0-9
-	1, 0, 0, 1, 0, 0, 0, 0, 0, 0, //10-19    //'\r' & '\n' are stop conditions
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //20-29
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //30-39
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //40-49
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //50-59
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //60-69
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //70-79
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //80-89
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //90-99
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //100-109
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //110-119
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //120-129
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //130-139
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //140-149
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //150-159
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //160-169
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //170-179
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //180-189
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //190-199
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //200-209
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //210-219
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //220-229
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //230-239
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //240-249
-	0, 0, 0, 0, 0, 0,             //250-255
-}
+// stop when you hit an eol, '\r' & '\n' are stop conditions
+var sEOLMask = MaskOf("\r\n")
 var EOLMask = sEOLMask
 
-// skip over whitespace
-var sWhitespaceMask = []uint8{
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 0, //0-9      // stop on '\t'
-	0, 0, 0, 0, 1, 1, 1, 1, 1, 1, //10-19    // '\r', \v', '\f' & '\n'
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //20-29
-	1, 1, 0, 1, 1, 1, 1, 1, 1, 1, //30-39   //  ' '
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //40-49
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //50-59
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //60-69
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //70-79
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //80-89
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //90-99
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //100-109
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //110-119
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //120-129
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //130-139
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //140-149
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //150-159
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //160-169
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //170-179
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //180-189
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //190-199
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //200-209
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //210-219
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //220-229
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //230-239
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, //240-249
-	1, 1, 1, 1, 1, 1,             //250-255
-}
+// skip over whitespace, stop on everything but '\t', '\n', '\v', '\f', '\r' & ' '
+var sWhitespaceMask = sEOLWhitespaceMask.Invert()
 var WhitespaceMask = sWhitespaceMask
 
+// skip over spaces and tabs but not over an EOL
+var sLinearWhitespaceMask = MaskOf(" \t").Invert()
+var LinearWhitespaceMask = sLinearWhitespaceMask
+
 // stop when you hit an EOL or whitespace
-var sEOLWhitespaceMask = []uint8{
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 1, //0-9     // \t is a stop
-	1, 1, 1, 1, 0, 0, 0, 0, 0, 0, //10-19    //'\r' & '\n' are stop conditions
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //20-29
-	0, 0, 1, 0, 0, 0, 0, 0, 0, 0, //30-39   ' '  is a stop
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //40-49
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //50-59
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //60-69
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //70-79
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //80-89
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //90-99
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //100-109
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //110-119
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //120-129
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //130-139
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //140-149
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //150-159
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //160-169
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //170-179
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //180-189
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //190-199
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //200-209
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //210-219
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //220-229
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //230-239
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //240-249
-	0, 0, 0, 0, 0, 0,             //250-255
-}
+var sEOLWhitespaceMask = MaskOf("\t\n\v\f\r ")
 var EOLWhitespaceMask = sEOLWhitespaceMask
 
 // stop when you hit an EOL, ? or whitespace
-var sEOLWhitespaceQueryMask = []uint8{
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 1, //0-9     // \t is a stop
-	1, 1, 1, 1, 0, 0, 0, 0, 0, 0, //10-19    //'\r' & '\n' are stop conditions
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //20-29
-	0, 0, 1, 0, 0, 0, 0, 0, 0, 0, //30-39   ' '  is a stop
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //40-49
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //50-59
-	0, 0, 0, 1, 0, 0, 0, 0, 0, 0, //60-69  ? is a stop
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //70-79
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //80-89
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //90-99
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //100-109
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //110-119
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //120-129
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //130-139
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //140-149
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //150-159
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //160-169
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //170-179
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //180-189
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //190-199
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //200-209
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //210-219
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //220-229
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //230-239
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //240-249
-	0, 0, 0, 0, 0, 0,             //250-255
-}
+var sEOLWhitespaceQueryMask = sEOLWhitespaceMask.Union(MaskOf("?"))
 var EOLWhitespaceQueryMask = sEOLWhitespaceQueryMask
 
-var sURLStopConditions = []uint8{
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 1, //0-9      //'\t' is a stop condition
-	1, 0, 0, 1, 0, 0, 0, 0, 0, 0, //10-19    //'\r' & '\n' are stop conditions
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //20-29
-	0, 0, 1, 0, 0, 0, 0, 0, 0, 0, //30-39    //' '
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //40-49
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //50-59
-	0, 0, 0, 1, 0, 0, 0, 0, 0, 0, //60-69   //'?'
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //70-79
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //80-89
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //90-99
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //100-109
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //110-119
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //120-129
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //130-139
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //140-149
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //150-159
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //160-169
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //170-179
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //180-189
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //190-199
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //200-209
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //210-219
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //220-229
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //230-239
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, //240-249
-	0, 0, 0, 0, 0, 0,             //250-255
-}
+// stop at the end of a url: '\t', '\r', '\n', ' ' & '?'
+var sURLStopConditions = MaskOf("\t\r\n ?")
 var URLStopConditions = sURLStopConditions
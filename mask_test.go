@@ -0,0 +1,67 @@
+package commonutilities
+
+import (
+	"testing"
+)
+
+func TestMask(t *testing.T) {
+	if WordMask.Invert() != NonWordMask {
+		t.Error("WordMask.Invert() != NonWordMask")
+	}
+	if WordMask.Intersect(NonWordMask) != (Mask{}) {
+		t.Error("WordMask.Intersect(NonWordMask) is not empty")
+	}
+	if EOLMask.Union(URLStopConditions) != URLStopConditions {
+		t.Error("EOLMask is not a subset of URLStopConditions")
+	}
+	if MaskRange('0', '9') != MaskFunc(func(c byte) bool { return c >= '0' && c <= '9' }) {
+		t.Error("MaskRange('0', '9') != MaskFunc(isDigit)")
+	}
+	if all := MaskRange(0, 255); all != (Mask{}).Invert() {
+		t.Errorf("MaskRange(0, 255) = %x", all)
+	}
+
+	var tests = []struct {
+		mask Mask
+		in   byte
+		want bool
+	}{
+		{MaskOf("?;"), ';', true},
+		{MaskOf("?;"), ':', false},
+		{WordMask, '_', true},
+		{WordMask, '9', false},
+		{WhitespaceMask, '\t', false},
+		{WhitespaceMask, 0xff, true},
+		{EOLWhitespaceQueryMask, '?', true},
+	}
+	for _, test := range tests {
+		if got := test.mask.Contains(test.in); got != test.want {
+			t.Errorf("Contains(%q) = %v", test.in, got)
+		}
+	}
+}
+
+func TestRuneMask(t *testing.T) {
+	var tests = []struct {
+		mask RuneMask
+		in   rune
+		want bool
+	}{
+		{WordRuneMask, '摄', true},
+		{WordRuneMask, 'é', true},
+		{WordRuneMask, '́', true}, // combining acute accent
+		{WordRuneMask, '_', true},
+		{WordRuneMask, '1', false},
+		{WordRuneMask, '，', false},
+		{NonWordRuneMask, '/', true},
+		{EOLMask.Runes(), '\n', true},
+		{EOLMask.Runes(), 'Ċ', false}, // U+010A, whose low byte is '\n'
+		{MaskRange(0x80, 0xff).Runes(), 'é', false},
+		{DigitMask.Runes().Union(WordRuneMask), '7', true},
+	}
+	for _, test := range tests {
+		if got := test.mask.Contains(test.in); got != test.want {
+			t.Errorf("Contains(%q) = %v", test.in, got)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package commonutilities
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ParseError reports where and why parsing failed.
+type ParseError struct {
+	// Offset is the byte offset from the beginning of the parsed buffer
+	Offset int
+	// Line, Column and RuneColumn count from 1. Column counts bytes and
+	// RuneColumn counts UTF-8 characters.
+	Line       int
+	Column     int
+	RuneColumn int
+	// Expected describes what should have been at Offset, e.g. "':'" or "integer"
+	Expected string
+	// Snippet is the input around Offset, cut at line boundaries
+	Snippet string
+	// Err is the underlying error, if any. It replaces Expected in the message.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	msg := fmt.Sprintf("line %d, column %d: ", e.Line, e.Column)
+	if e.Err != nil {
+		msg += e.Err.Error()
+	} else {
+		msg += "expected " + e.Expected
+	}
+	if e.Snippet != "" {
+		msg += fmt.Sprintf(" near %q", e.Snippet)
+	}
+	return msg
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// how many bytes of context a snippet holds on each side of the error
+const snippetRadius = 20
+
+// NewParseError
+// Returns a ParseError at the current position, e.g. after a Consume* call
+// returned something the caller cannot accept.
+func (s *Parser[T]) NewParseError(expected string) *ParseError {
+	offset := s.startIndex
+	if offset < 0 {
+		offset = 0
+	}
+	lineStart := s.lineStart(offset)
+	lineEnd := offset
+	for lineEnd < len(s.buffer) && s.buffer[lineEnd] != '\r' && s.buffer[lineEnd] != '\n' {
+		lineEnd++
+	}
+
+	snippetStart, snippetEnd := lineStart, lineEnd
+	if offset-snippetStart > snippetRadius {
+		snippetStart = offset - snippetRadius
+	}
+	if snippetEnd-offset > snippetRadius {
+		snippetEnd = offset + snippetRadius
+	}
+	return &ParseError{
+		Offset:     offset,
+		Line:       s.curLineNumber,
+		Column:     s.curColumn,
+		RuneColumn: s.curRuneColumn,
+		Expected:   expected,
+		Snippet:    string(s.buffer[snippetStart:snippetEnd]),
+	}
+}
+
+// failAt moves back to mark and returns a ParseError there, or
+// ErrNeedMoreData if an incremental parser ran dry.
+func (s *Parser[T]) failAt(mark ParserMark, expected string) error {
+	s.Reset(mark)
+	if s.needMoreData {
+		return ErrNeedMoreData
+	}
+	return s.NewParseError(expected)
+}
+
+// The *Err variants below behave like the method they are named after but
+// return an error instead of an empty token or false. On failure nothing is
+// consumed and the *ParseError points at where the token should have
+// started. An incremental parser that needs more data returns
+// ErrNeedMoreData.
+
+func (s *Parser[T]) ConsumeWordErr() (T, error) {
+	return s.ConsumeUntilErr(sNonWordMask, "word")
+}
+
+// ConsumeUntilErr fails if there is nothing before the first byte in
+// inMask. expected describes the token for the error message.
+func (s *Parser[T]) ConsumeUntilErr(inMask Mask, expected string) (T, error) {
+	mark := s.Mark()
+	if outString := s.ConsumeUntil(inMask); len(outString) > 0 {
+		return outString, nil
+	}
+	return s.empty(), s.failAt(mark, expected)
+}
+
+// ConsumeUntilStopErr fails if inStop is not found.
+func (s *Parser[T]) ConsumeUntilStopErr(inStop byte) (T, error) {
+	mark := s.Mark()
+	outString := s.ConsumeUntilStop(inStop)
+	if !s.needMoreData && !s.ParserIsEmpty() {
+		return outString, nil
+	}
+	return s.empty(), s.failAt(mark, fmt.Sprintf("%q", inStop))
+}
+
+// ConsumeLengthErr fails if fewer than inLength bytes are left.
+func (s *Parser[T]) ConsumeLengthErr(inLength int) (T, error) {
+	mark := s.Mark()
+	if inLength >= 0 && s.GetDataRemaining() >= inLength {
+		return s.ConsumeLength(inLength), nil
+	}
+	if s.incremental {
+		s.needMoreData = true
+	}
+	return s.empty(), s.failAt(mark, fmt.Sprintf("%d bytes", inLength))
+}
+
+// ConsumeIntegerErr also fails if the integer does not fit in 32 bits.
+func (s *Parser[T]) ConsumeIntegerErr() (T, uint32, error) {
+	mark := s.Mark()
+	theValue, err := s.ConsumeUint64()
+	if err != nil {
+		return s.empty(), 0, err
+	}
+	if theValue > math.MaxUint32 {
+		return s.empty(), 0, s.rangeError(mark, "integer", fmt.Errorf("integer overflows 32 bits: %w", strconv.ErrRange))
+	}
+	return s.buffer[mark.startIndex:s.startIndex], uint32(theValue), nil
+}
+
+// ConsumeFloatErr also fails if the number is too large for a float64.
+func (s *Parser[T]) ConsumeFloatErr() (float64, error) {
+	mark := s.Mark()
+	theFloat, err := s.consumeFloat()
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, s.rangeError(mark, "number", fmt.Errorf("number overflows float64: %w", strconv.ErrRange))
+	}
+	return theFloat, err
+}
+
+func (s *Parser[T]) ConsumeNPTErr() (float32, error) {
+	mark := s.Mark()
+	theFloat := s.ConsumeNPT()
+	if !s.needMoreData && s.startIndex != mark.startIndex {
+		return theFloat, nil
+	}
+	return 0, s.failAt(mark, "npt time")
+}
+
+func (s *Parser[T]) ConsumeNPTDurationErr() (time.Duration, error) {
+	mark := s.Mark()
+	if theDuration, ok := s.ConsumeNPTDuration(); ok {
+		return theDuration, nil
+	}
+	return 0, s.failAt(mark, "npt time")
+}
+
+func (s *Parser[T]) ConsumeEOLErr() (T, error) {
+	mark := s.Mark()
+	if outString := s.ConsumeEOL(); len(outString) > 0 {
+		return outString, nil
+	}
+	return s.empty(), s.failAt(mark, "end of line")
+}
+
+func (s *Parser[T]) ExpectErr(stopChar byte) error {
+	mark := s.Mark()
+	if s.Expect(stopChar) {
+		return nil
+	}
+	return s.failAt(mark, fmt.Sprintf("%q", stopChar))
+}
+
+func (s *Parser[T]) ExpectEOLErr() error {
+	mark := s.Mark()
+	if s.ExpectEOL() {
+		return nil
+	}
+	return s.failAt(mark, "end of line")
+}
+
+// GetThruErr fails if stopChar is not found.
+func (s *Parser[T]) GetThruErr(stopChar byte) (T, error) {
+	mark := s.Mark()
+	if outString, ok := s.GetThru(stopChar); ok {
+		return outString, nil
+	}
+	return s.empty(), s.failAt(mark, fmt.Sprintf("%q", stopChar))
+}
+
+// GetThruEOLErr fails if the line has no end of line.
+func (s *Parser[T]) GetThruEOLErr() (T, error) {
+	mark := s.Mark()
+	if outString, ok := s.GetThruEOL(); ok {
+		return outString, nil
+	}
+	return s.empty(), s.failAt(mark, "end of line")
+}
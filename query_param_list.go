@@ -0,0 +1,167 @@
+package commonutilities
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// QueryParam is one name=value pair of a query string.
+type QueryParam struct {
+	Name  string
+	Value string
+}
+
+// QueryParamList holds the parameters of a query string such as
+// "channel=1&token=888888", in the spirit of Darwin's QueryParamList. Names
+// and values are percent-decoded, order and duplicates are preserved and
+// names are case sensitive.
+type QueryParamList struct {
+	params []QueryParam
+}
+
+// stop at the end of a query parameter name
+var sQueryNameStopConditions = MaskOf("=&")
+
+// NewQueryParamList parses a query string. A leading '?' is skipped.
+func NewQueryParamList(query string) (*QueryParamList, error) {
+	list := &QueryParamList{}
+	parser := New(query)
+	parser.Expect('?')
+	for !parser.ParserIsEmpty() {
+		name := parser.ConsumeUntil(sQueryNameStopConditions)
+		var value string
+		if parser.Expect('=') {
+			value = parser.ConsumeUntilStop('&')
+		}
+		parser.Expect('&')
+		if name == "" && value == "" {
+			continue
+		}
+
+		var param QueryParam
+		var err error
+		if param.Name, err = decodeQueryComponent(name); err != nil {
+			return nil, err
+		}
+		if param.Value, err = decodeQueryComponent(value); err != nil {
+			return nil, err
+		}
+		list.params = append(list.params, param)
+	}
+	return list, nil
+}
+
+// decodeQueryComponent undoes %XX escapes. A '+' is kept as is, RTSP URLs
+// are not form-encoded and base64 values such as "ab+cd==" hold '+'.
+func decodeQueryComponent(inString string) (string, error) {
+	if !strings.Contains(inString, "%") {
+		return inString, nil
+	}
+	var decoded strings.Builder
+	parser := New(inString)
+	for !parser.ParserIsEmpty() {
+		decoded.WriteString(parser.ConsumeUntilStop('%'))
+		if parser.Expect('%') {
+			hex := parser.ConsumeLength(2)
+			value, err := strconv.ParseUint(hex, 16, 8)
+			if len(hex) != 2 || err != nil {
+				return "", fmt.Errorf("malformed escape %q in %q", "%"+hex, inString)
+			}
+			decoded.WriteByte(byte(value))
+		}
+	}
+	return decoded.String(), nil
+}
+
+// Len returns the number of parameters.
+func (l *QueryParamList) Len() int { return len(l.params) }
+
+// Params returns the parameters in order.
+func (l *QueryParamList) Params() []QueryParam { return l.params }
+
+// Has returns true if there is a parameter called name.
+func (l *QueryParamList) Has(name string) bool {
+	_, ok := l.Get(name)
+	return ok
+}
+
+// Get returns the value of the first parameter called name.
+func (l *QueryParamList) Get(name string) (string, bool) {
+	for _, param := range l.params {
+		if param.Name == name {
+			return param.Value, true
+		}
+	}
+	return "", false
+}
+
+// GetAll returns the values of every parameter called name in order.
+func (l *QueryParamList) GetAll(name string) (values []string) {
+	for _, param := range l.params {
+		if param.Name == name {
+			values = append(values, param.Value)
+		}
+	}
+	return
+}
+
+// GetString returns the value of the first parameter called name, or an
+// error if there is none.
+func (l *QueryParamList) GetString(name string) (string, error) {
+	value, ok := l.Get(name)
+	if !ok {
+		return "", fmt.Errorf("query parameter %q not found", name)
+	}
+	return value, nil
+}
+
+// GetInt returns the first parameter called name as an int.
+func (l *QueryParamList) GetInt(name string) (int, error) {
+	value, err := l.GetString(name)
+	if err != nil {
+		return 0, err
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("query parameter %s=%q is not an integer", name, value)
+	}
+	return number, nil
+}
+
+// GetUint returns the first parameter called name as a uint64.
+func (l *QueryParamList) GetUint(name string) (uint64, error) {
+	value, err := l.GetString(name)
+	if err != nil {
+		return 0, err
+	}
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("query parameter %s=%q is not an unsigned integer", name, value)
+	}
+	return number, nil
+}
+
+// GetBool returns the first parameter called name as a bool. A parameter
+// without a value, as in "?debug", is true.
+func (l *QueryParamList) GetBool(name string) (bool, error) {
+	value, err := l.GetString(name)
+	if err != nil || value == "" {
+		return err == nil, err
+	}
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("query parameter %s=%q is not a boolean", name, value)
+	}
+	return flag, nil
+}
+
+// QueryParams parses the query of the request line.
+func (r *Request) QueryParams() (*QueryParamList, error) {
+	return NewQueryParamList(r.Query)
+}
+
+// QueryParams parses RawQuery.
+func (u *RTSPURL) QueryParams() (*QueryParamList, error) {
+	return NewQueryParamList(u.RawQuery)
+}
@@ -0,0 +1,228 @@
+package commonutilities
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RangeUnit is the time format of a Range header, RFC 2326 section 3.5-3.7.
+type RangeUnit string
+
+const (
+	RangeNPT         RangeUnit = "npt"
+	RangeSMPTE       RangeUnit = "smpte"
+	RangeSMPTE30Drop RangeUnit = "smpte-30-drop"
+	RangeSMPTE25     RangeUnit = "smpte-25"
+	RangeClock       RangeUnit = "clock"
+)
+
+// clock= and time= values, e.g. 19961108T142300.25Z
+const clockLayout = "20060102T150405.999999999Z"
+
+// Timecode is an SMPTE hours:minutes:seconds:frames.subframes time.
+type Timecode struct {
+	Hours     int
+	Minutes   int
+	Seconds   int
+	Frames    int
+	Subframes int
+}
+
+// RangePoint is the start or the end of a Range.
+type RangePoint struct {
+	// Set is false for the missing end of an open range such as "npt=0.000-"
+	Set bool
+	// Now is the npt time "now", used for live streams
+	Now bool
+	// Offset is the npt or smpte time from the beginning of the media
+	Offset time.Duration
+	// Timecode is the smpte time as sent. String writes Timecode, not
+	// Offset, for the smpte units.
+	Timecode Timecode
+	// Clock is the absolute time of the clock unit
+	Clock time.Time
+}
+
+// Range is a parsed Range header.
+type Range struct {
+	Unit  RangeUnit
+	Start RangePoint
+	End   RangePoint
+	// Time is the optional ";time=" parameter, the wall clock time at which
+	// the request should take effect
+	Time time.Time
+}
+
+// stop at the end of a range time
+var sRangeTimeStopConditions = MaskOf("-")
+
+// ParseRange parses a Range header such as "npt=0.000-",
+// "smpte-25=10:07:00-10:07:33:05.01" or "clock=19961108T142300Z-".
+func ParseRange(value string) (r Range, err error) {
+	elements, err := ParseHeaderValue(value)
+	if err != nil || len(elements) != 1 {
+		return r, fmt.Errorf("malformed range %q", value)
+	}
+	parser := New(elements[0].Token)
+	unit, ok := parser.GetThru('=')
+	r.Unit = RangeUnit(strings.ToLower(strings.TrimSpace(unit)))
+	switch {
+	case !ok:
+		return r, fmt.Errorf("malformed range %q", value)
+	case r.Unit != RangeNPT && r.Unit != RangeSMPTE && r.Unit != RangeSMPTE30Drop &&
+		r.Unit != RangeSMPTE25 && r.Unit != RangeClock:
+		return r, fmt.Errorf("unsupported range unit %q", unit)
+	}
+
+	if r.Start, err = parseRangePoint(r.Unit, parser.ConsumeUntil(sRangeTimeStopConditions)); err != nil {
+		return r, err
+	}
+	if !parser.Expect('-') {
+		return r, fmt.Errorf("malformed range %q", value)
+	}
+	if r.End, err = parseRangePoint(r.Unit, parser.ConsumeUntil(sRangeTimeStopConditions)); err != nil {
+		return r, err
+	}
+	if !r.Start.Set && (!r.End.Set || r.Unit != RangeNPT) {
+		return r, fmt.Errorf("malformed range %q", value)
+	}
+
+	if !parser.ParserIsEmpty() {
+		return r, fmt.Errorf("malformed range %q", value)
+	}
+	if param, ok := elements[0].Param("time"); ok {
+		if r.Time, err = time.Parse(clockLayout, param); err != nil {
+			return r, fmt.Errorf("malformed range time %q", param)
+		}
+	}
+	return r, nil
+}
+
+func parseRangePoint(unit RangeUnit, inString string) (point RangePoint, err error) {
+	inString = strings.TrimSpace(inString)
+	if inString == "" {
+		return point, nil
+	}
+	point.Set = true
+
+	switch unit {
+	case RangeNPT:
+		if inString == "now" {
+			point.Now = true
+			return point, nil
+		}
+		parser := New(inString)
+		var ok bool
+		if point.Offset, ok = parser.ConsumeNPTDuration(); !ok || !parser.ParserIsEmpty() {
+			return point, fmt.Errorf("malformed npt time %q", inString)
+		}
+	case RangeClock:
+		if point.Clock, err = time.Parse(clockLayout, inString); err != nil {
+			return point, fmt.Errorf("malformed clock time %q", inString)
+		}
+	default:
+		if point.Timecode, err = parseTimecode(inString); err != nil {
+			return point, err
+		}
+		if point.Offset, err = point.Timecode.Duration(unit); err != nil {
+			return point, err
+		}
+	}
+	return point, nil
+}
+
+func parseTimecode(inString string) (timecode Timecode, err error) {
+	parser := New(inString)
+	fields := []*int{&timecode.Hours, &timecode.Minutes, &timecode.Seconds, &timecode.Frames}
+	count := 0
+	for _, field := range fields {
+		digits, value := parser.ConsumeInteger()
+		if digits == "" || len(digits) > 2 {
+			return timecode, fmt.Errorf("malformed smpte time %q", inString)
+		}
+		*field = int(value)
+		if count++; count == len(fields) || !parser.Expect(':') {
+			break
+		}
+	}
+	if count == len(fields) && parser.Expect('.') {
+		digits, value := parser.ConsumeInteger()
+		if digits == "" || len(digits) > 2 {
+			return timecode, fmt.Errorf("malformed smpte time %q", inString)
+		}
+		timecode.Subframes = int(value)
+	}
+	if count < 3 || !parser.ParserIsEmpty() {
+		return timecode, fmt.Errorf("malformed smpte time %q", inString)
+	}
+	return timecode, nil
+}
+
+// Duration converts the timecode to the time from the beginning of the media
+// at the frame rate of unit: 30, 29.97 with dropped frame numbers, or 25
+// frames per second.
+func (tc Timecode) Duration(unit RangeUnit) (time.Duration, error) {
+	framesPerSecond := 30
+	if unit == RangeSMPTE25 {
+		framesPerSecond = 25
+	}
+	if tc.Minutes > 59 || tc.Seconds > 59 || tc.Frames >= framesPerSecond || tc.Subframes > 99 {
+		return 0, fmt.Errorf("smpte time %s out of range", tc)
+	}
+
+	seconds := int64(tc.Hours)*3600 + int64(tc.Minutes)*60 + int64(tc.Seconds)
+	if unit != RangeSMPTE30Drop {
+		hundredthsOfFrames := int64(tc.Frames)*100 + int64(tc.Subframes)
+		return time.Duration(seconds)*time.Second + time.Duration(hundredthsOfFrames*int64(time.Second)/(100*int64(framesPerSecond))), nil
+	}
+
+	// frames 0 and 1 are skipped at the start of every minute except every tenth
+	if tc.Seconds == 0 && tc.Frames < 2 && tc.Minutes%10 != 0 {
+		return 0, fmt.Errorf("smpte time %s is a dropped frame", tc)
+	}
+	totalMinutes := int64(tc.Hours)*60 + int64(tc.Minutes)
+	frameNumber := seconds*30 + int64(tc.Frames) - 2*(totalMinutes-totalMinutes/10)
+	// each frame lasts 1001/30000 seconds
+	return time.Duration((frameNumber*100 + int64(tc.Subframes)) * 1001000 / 3), nil
+}
+
+// String formats the timecode as hh:mm:ss[:ff[.ss]].
+func (tc Timecode) String() string {
+	timecode := fmt.Sprintf("%02d:%02d:%02d", tc.Hours, tc.Minutes, tc.Seconds)
+	if tc.Frames != 0 || tc.Subframes != 0 {
+		timecode += fmt.Sprintf(":%02d", tc.Frames)
+	}
+	if tc.Subframes != 0 {
+		timecode += fmt.Sprintf(".%02d", tc.Subframes)
+	}
+	return timecode
+}
+
+// String formats the Range header value.
+func (r Range) String() string {
+	value := string(r.Unit) + "=" + r.Start.format(r.Unit) + "-" + r.End.format(r.Unit)
+	if !r.Time.IsZero() {
+		value += ";time=" + r.Time.UTC().Format(clockLayout)
+	}
+	return value
+}
+
+func (p RangePoint) format(unit RangeUnit) string {
+	switch {
+	case !p.Set:
+		return ""
+	case unit == RangeClock:
+		return p.Clock.UTC().Format(clockLayout)
+	case unit != RangeNPT:
+		return p.Timecode.String()
+	case p.Now:
+		return "now"
+	}
+	// npt seconds with at least millisecond precision, e.g. 0.000 or 12.3456
+	fraction := strings.TrimRight(fmt.Sprintf("%09d", p.Offset%time.Second), "0")
+	for len(fraction) < 3 {
+		fraction += "0"
+	}
+	return fmt.Sprintf("%d.%s", p.Offset/time.Second, fraction)
+}
@@ -0,0 +1,71 @@
+package commonutilities
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RTPInfo is one stream of an RTP-Info header, RFC 2326 section 12.33, e.g.
+// "url=rtsp://foo.com/bar.avi/streamid=0;seq=45102;rtptime=12345678".
+type RTPInfo struct {
+	URL        string
+	Seq        uint16
+	HasSeq     bool
+	RTPTime    uint32
+	HasRTPTime bool
+}
+
+// ParseRTPInfo parses an RTP-Info header into one RTPInfo per stream.
+func ParseRTPInfo(value string) ([]RTPInfo, error) {
+	elements, err := ParseHeaderValue(value)
+	if err != nil {
+		return nil, fmt.Errorf("malformed RTP-Info %q: %w", value, err)
+	}
+	infos := make([]RTPInfo, 0, len(elements))
+	for _, element := range elements {
+		var info RTPInfo
+		var ok bool
+		if info.URL, ok = strings.CutPrefix(element.Token, "url="); !ok || info.URL == "" {
+			return nil, fmt.Errorf("RTP-Info stream %q has no url", element.String())
+		}
+		for _, param := range element.Params {
+			switch param.Name {
+			case "seq":
+				var seq uint64
+				seq, err = strconv.ParseUint(param.Value, 10, 16)
+				info.Seq, info.HasSeq = uint16(seq), true
+			case "rtptime":
+				var rtpTime uint64
+				rtpTime, err = strconv.ParseUint(param.Value, 10, 32)
+				info.RTPTime, info.HasRTPTime = uint32(rtpTime), true
+			}
+			if err != nil {
+				return nil, fmt.Errorf("malformed RTP-Info parameter %q", param.String())
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// String formats the stream for an RTP-Info header.
+func (r RTPInfo) String() string {
+	info := HeaderElement{Token: "url=" + r.URL}
+	if r.HasSeq {
+		info.AddParam("seq", strconv.FormatUint(uint64(r.Seq), 10))
+	}
+	if r.HasRTPTime {
+		info.AddParam("rtptime", strconv.FormatUint(uint64(r.RTPTime), 10))
+	}
+	return info.String()
+}
+
+// FormatRTPInfo formats an RTP-Info header from its streams.
+func FormatRTPInfo(infos []RTPInfo) string {
+	streams := make([]string, len(infos))
+	for i, info := range infos {
+		streams[i] = info.String()
+	}
+	return strings.Join(streams, ",")
+}
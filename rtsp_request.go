@@ -0,0 +1,100 @@
+package commonutilities
+
+// RTSP methods, RFC 2326 section 10.
+const (
+	MethodDescribe     = "DESCRIBE"
+	MethodAnnounce     = "ANNOUNCE"
+	MethodGetParameter = "GET_PARAMETER"
+	MethodOptions      = "OPTIONS"
+	MethodPause        = "PAUSE"
+	MethodPlay         = "PLAY"
+	MethodRecord       = "RECORD"
+	MethodRedirect     = "REDIRECT"
+	MethodSetup        = "SETUP"
+	MethodSetParameter = "SET_PARAMETER"
+	MethodTeardown     = "TEARDOWN"
+)
+
+// Request is a parsed RTSP request.
+type Request struct {
+	Method string
+	// URL is the absolute URL from the request line without the query,
+	// e.g. rtsp://192.168.1.105:8554/test.264/track1
+	URL string
+	// URI is the path part of URL, e.g. /test.264/track1
+	URI string
+	// Query is whatever followed the '?' in the request line, e.g. channel=1&token=888888
+	Query   string
+	Version string
+	Header  Header
+	// Body holds Content-Length bytes following the header block
+	Body string
+}
+
+// ParseRequest parses the request line, the header block and the body of an
+// RTSP request. It returns ErrNeedMoreData if inString ends before the
+// request does.
+func ParseRequest(inString string) (*Request, error) {
+	parser := New(inString)
+	request := &Request{}
+
+	request.Method = parser.ConsumeWord()
+	if request.Method == "" || (parser.PeekFast() != ' ' && parser.PeekFast() != '\t') {
+		return nil, requestLineError(parser, "method followed by a space")
+	}
+	parser.ConsumeWhitespace()
+
+	request.URL = parser.ConsumeUntil(sURLStopConditions)
+	if request.URL == "" {
+		return nil, requestLineError(parser, "request URL")
+	}
+	if parser.Expect('?') {
+		request.Query = parser.ConsumeUntilWhitespace()
+	}
+	request.URI = requestURI(request.URL)
+	parser.ConsumeWhitespace()
+
+	versionMark := parser.Mark()
+	request.Version = parser.ConsumeUntil(sEOLWhitespaceMask)
+	if !isRTSPVersion(request.Version) {
+		if !parser.ParserIsEmpty() {
+			parser.Reset(versionMark)
+		}
+		return nil, requestLineError(parser, "RTSP version")
+	}
+	if !parser.ExpectEOL() {
+		return nil, requestLineError(parser, "end of line")
+	}
+
+	var err error
+	if request.Header, err = parseHeader(parser); err != nil {
+		return nil, err
+	}
+	if request.Body, err = parseBody(parser, request.Header); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func requestLineError(parser *StringParser, expected string) error {
+	if parser.ParserIsEmpty() {
+		return ErrNeedMoreData
+	}
+	return parser.NewParseError(expected)
+}
+
+// requestURI strips the scheme and host from an absolute URL.
+func requestURI(url string) string {
+	if url[0] == '/' || url[0] == '*' {
+		return url
+	}
+	parser := New(url)
+	if _, ok := parser.GetThru(':'); !ok || !parser.Expect('/') || !parser.Expect('/') {
+		return url
+	}
+	parser.ConsumeUntilStop('/')
+	if parser.ParserIsEmpty() {
+		return "/"
+	}
+	return parser.ConsumeLength(parser.GetDataRemaining())
+}
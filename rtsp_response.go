@@ -0,0 +1,77 @@
+package commonutilities
+
+import (
+	"strings"
+)
+
+// Response is a parsed RTSP response.
+type Response struct {
+	Version    string
+	StatusCode int
+	Reason     string
+	Header     Header
+	// Body holds Content-Length bytes following the header block
+	Body string
+}
+
+// ParseResponse parses the status line, the header block and the body of an
+// RTSP response. Errors cite the line they were found on. It returns
+// ErrNeedMoreData if inString ends before the response does.
+func ParseResponse(inString string) (*Response, error) {
+	parser := New(inString)
+	response := &Response{}
+
+	versionMark := parser.Mark()
+	response.Version = parser.ConsumeUntil(sEOLWhitespaceMask)
+	if !isRTSPVersion(response.Version) {
+		return nil, statusLineError(parser, versionMark, "RTSP version")
+	}
+	parser.ConsumeUntil(sLinearWhitespaceMask)
+
+	statusCodeMark := parser.Mark()
+	digits, statusCode := parser.ConsumeInteger()
+	if len(digits) != 3 {
+		return nil, statusLineError(parser, statusCodeMark, "3 digit status code")
+	}
+	response.StatusCode = int(statusCode)
+	parser.ConsumeUntil(sLinearWhitespaceMask)
+
+	response.Reason = strings.TrimSpace(parser.ConsumeUntil(sEOLMask))
+	if !parser.ExpectEOL() {
+		return nil, ErrNeedMoreData
+	}
+
+	var err error
+	if response.Header, err = parseHeader(parser); err != nil {
+		return nil, err
+	}
+	if response.Body, err = parseBody(parser, response.Header); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// isRTSPVersion accepts "RTSP/<major>.<minor>", or a bare "RTSP" as some
+// servers send.
+func isRTSPVersion(version string) bool {
+	if version == "RTSP" {
+		return true
+	}
+	parser := New(version)
+	if !parser.ExpectString("RTSP/") {
+		return false
+	}
+	if _, err := parser.ConsumeUint64(); err != nil || !parser.Expect('.') {
+		return false
+	}
+	_, err := parser.ConsumeUint64()
+	return err == nil && parser.ParserIsEmpty()
+}
+
+func statusLineError(parser *StringParser, mark ParserMark, expected string) error {
+	if parser.ParserIsEmpty() {
+		return ErrNeedMoreData
+	}
+	parser.Reset(mark)
+	return parser.NewParseError(expected)
+}
@@ -0,0 +1,142 @@
+package commonutilities
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// RTSPURL is a parsed rtsp://, rtsps:// or rtspu:// URL.
+type RTSPURL struct {
+	// Scheme is lower case, e.g. rtsp
+	Scheme string
+	// User and Password are percent-decoded
+	User        string
+	Password    string
+	HasPassword bool
+	// Host is a name, an IPv4 address or an IPv6 address without brackets
+	Host string
+	// Port is the explicit port or the default port of the scheme
+	Port int
+	// Path is the full path, e.g. /test.264/track1
+	Path string
+	// StreamPath is Path without Track, e.g. /test.264
+	StreamPath string
+	// Track is the control suffix of a SETUP URL, e.g. track1, trackID=1 or
+	// streamid=0, or "" if the last path segment is not a track
+	Track string
+	// RawQuery is what followed '?', e.g. channel=1&token=888888
+	RawQuery string
+}
+
+// default ports, RFC 2326 and RFC 7826
+var rtspDefaultPorts = map[string]int{"rtsp": 554, "rtspu": 554, "rtsps": 322}
+
+// stop at the end of the host and port
+var sURLAuthorityStopConditions = sURLStopConditions.Union(MaskOf("/"))
+
+// last path segments recognized as a track when followed by a number,
+// compared case-insensitively
+var rtspTrackPrefixes = []string{"trackid=", "streamid=", "stream=", "track"}
+
+// ParseRTSPURL parses an RTSP URL. Parsing stops at the first whitespace so
+// the URL can be handed over straight from a request line.
+func ParseRTSPURL(rawURL string) (*RTSPURL, error) {
+	parser := New(rawURL)
+	u := &RTSPURL{}
+
+	scheme, ok := parser.GetThru(':')
+	u.Scheme = strings.ToLower(scheme)
+	defaultPort, known := rtspDefaultPorts[u.Scheme]
+	if !ok || !known {
+		return nil, fmt.Errorf("unsupported scheme in url %q", rawURL)
+	}
+	if !parser.ExpectString("//") {
+		return nil, fmt.Errorf("missing // in url %q", rawURL)
+	}
+
+	authority := parser.ConsumeUntil(sURLAuthorityStopConditions)
+	if at := strings.LastIndexByte(authority, '@'); at >= 0 {
+		if err := u.setUserInfo(authority[:at]); err != nil {
+			return nil, fmt.Errorf("malformed user info in url %q", rawURL)
+		}
+		authority = authority[at+1:]
+	}
+
+	hostParser := New(authority)
+	if hostParser.Expect('[') {
+		var closed bool
+		if u.Host, closed = hostParser.GetThru(']'); !closed || u.Host == "" {
+			return nil, fmt.Errorf("malformed IPv6 host in url %q", rawURL)
+		}
+	} else {
+		u.Host = hostParser.ConsumeUntilStop(':')
+	}
+	u.Port = defaultPort
+	if hostParser.Expect(':') {
+		hostParser.SetMaxDigits(5)
+		port, err := hostParser.ConsumeUint64()
+		if err != nil || port > 65535 {
+			return nil, fmt.Errorf("malformed port in url %q", rawURL)
+		}
+		u.Port = int(port)
+	}
+	if u.Host == "" || !hostParser.ParserIsEmpty() {
+		return nil, fmt.Errorf("malformed host in url %q", rawURL)
+	}
+
+	u.Path = parser.ConsumeUntil(sURLStopConditions)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.StreamPath = u.Path
+	if slash := strings.LastIndexByte(u.Path, '/'); slash > 0 {
+		lastSegment := strings.ToLower(u.Path[slash+1:])
+		for _, prefix := range rtspTrackPrefixes {
+			if !strings.HasPrefix(lastSegment, prefix) {
+				continue
+			}
+			if digits, _ := New(lastSegment[len(prefix):]).ConsumeInteger(); digits != "" && len(digits) == len(lastSegment)-len(prefix) {
+				u.StreamPath, u.Track = u.Path[:slash], u.Path[slash+1:]
+				break
+			}
+		}
+	}
+
+	if parser.Expect('?') {
+		u.RawQuery = parser.ConsumeUntil(sEOLWhitespaceMask)
+	}
+	return u, nil
+}
+
+func (u *RTSPURL) setUserInfo(userInfo string) (err error) {
+	parser := New(userInfo)
+	user, hasPassword := parser.GetThru(':')
+	if u.User, err = url.PathUnescape(user); err != nil {
+		return err
+	}
+	if hasPassword {
+		u.HasPassword = true
+		u.Password, err = url.PathUnescape(parser.ConsumeLength(parser.GetDataRemaining()))
+	}
+	return err
+}
+
+// HostPort returns host:port with brackets around an IPv6 host.
+func (u *RTSPURL) HostPort() string {
+	if strings.Contains(u.Host, ":") {
+		return "[" + u.Host + "]:" + strconv.Itoa(u.Port)
+	}
+	return u.Host + ":" + strconv.Itoa(u.Port)
+}
+
+// String formats the URL without the user info, so it can be sent in a
+// request line. The port is always written.
+func (u *RTSPURL) String() string {
+	rawURL := u.Scheme + "://" + u.HostPort() + u.Path
+	if u.RawQuery != "" {
+		rawURL += "?" + u.RawQuery
+	}
+	return rawURL
+}
@@ -0,0 +1,206 @@
+package commonutilities
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Scan
+// Parses the data against pattern, storing the tokens in dst in order, e.g.
+//
+//	var method, url string
+//	var major, minor int
+//	err := parser.Scan("%w %u RTSP/%d.%d%eol", &method, &url, &major, &minor)
+//
+// A space in the pattern matches one or more spaces or tabs and other
+// characters must match exactly. The verbs and the Consume* method they map
+// to are:
+//
+//	%w   a word, ConsumeWordErr, into a *string or *[]byte
+//	%u   anything up to whitespace or an EOL, such as a URL, into a *string or *[]byte
+//	%s   the rest of the line, possibly empty, into a *string or *[]byte
+//	%q   a quoted-string, ConsumeQuotedString, into a *string or *[]byte
+//	%d   a decimal integer with an optional sign, ConsumeInt64, into any integer type
+//	%x   a hexadecimal integer, ConsumeHex, into any integer type
+//	%f   a floating point number, ConsumeFloatErr, into a *float64 or *float32
+//	%eol an end of line, ExpectEOLErr
+//	%%   a '%'
+//
+// On a mismatch nothing is consumed and the *ParseError points at the
+// mismatch, or the error is ErrNeedMoreData if an incremental parser ran
+// dry. An integer that does not fit its destination is reported with an
+// error wrapping strconv.ErrRange. dst may be partly filled on failure.
+func (s *Parser[T]) Scan(pattern string, dst ...any) error {
+	mark := s.Mark()
+	if err := s.scan(pattern, dst); err != nil {
+		s.Reset(mark)
+		return err
+	}
+	return nil
+}
+
+func (s *Parser[T]) scan(pattern string, dst []any) error {
+	for i := 0; i < len(pattern); {
+		switch pattern[i] {
+		case ' ':
+			i++
+			mark := s.Mark()
+			if whitespace := s.ConsumeUntil(sLinearWhitespaceMask); len(whitespace) == 0 {
+				return s.failAt(mark, "whitespace")
+			}
+		case '%':
+			verb := pattern[i+1:]
+			switch {
+			case strings.HasPrefix(verb, "eol"):
+				verb = "eol"
+			case verb == "":
+				return fmt.Errorf("pattern %q ends with %%", pattern)
+			default:
+				verb = verb[:1]
+			}
+			i += 1 + len(verb)
+			if verb == "%" {
+				if err := s.ExpectErr('%'); err != nil {
+					return err
+				}
+				continue
+			}
+			if verb == "eol" {
+				if err := s.ExpectEOLErr(); err != nil {
+					return err
+				}
+				continue
+			}
+			if len(dst) == 0 {
+				return fmt.Errorf("no destination for %%%s in %q", verb, pattern)
+			}
+			if err := s.scanVerb(verb[0], dst[0]); err != nil {
+				return err
+			}
+			dst = dst[1:]
+		default:
+			literalEnd := i
+			for literalEnd < len(pattern) && pattern[literalEnd] != ' ' && pattern[literalEnd] != '%' {
+				literalEnd++
+			}
+			literal := pattern[i:literalEnd]
+			i = literalEnd
+			mark := s.Mark()
+			if !s.ExpectString(literal) {
+				return s.failAt(mark, strconv.Quote(literal))
+			}
+		}
+	}
+	if len(dst) != 0 {
+		return fmt.Errorf("%d destinations left over by pattern %q", len(dst), pattern)
+	}
+	return nil
+}
+
+// scanVerb consumes the token of one verb and stores it in dst.
+func (s *Parser[T]) scanVerb(verb byte, dst any) error {
+	mark := s.Mark()
+	var token T
+	var err error
+	switch verb {
+	case 'w':
+		token, err = s.ConsumeWordErr()
+	case 'u':
+		token, err = s.ConsumeUntilErr(sEOLWhitespaceMask, "non-whitespace")
+	case 's':
+		token = s.ConsumeUntil(sEOLMask)
+		if s.needMoreData {
+			err = s.failAt(mark, "end of line")
+		}
+	case 'q':
+		token, err = s.ConsumeQuotedString()
+	case 'd':
+		var theValue int64
+		if theValue, err = s.ConsumeInt64(); err == nil {
+			err = s.storeInteger(mark, dst, uint64(theValue), theValue < 0)
+		}
+		return err
+	case 'x':
+		var theValue uint64
+		if theValue, err = s.ConsumeHex(); err == nil {
+			err = s.storeInteger(mark, dst, theValue, false)
+		}
+		return err
+	case 'f':
+		var theFloat float64
+		if theFloat, err = s.ConsumeFloatErr(); err == nil {
+			err = s.storeFloat(mark, dst, theFloat)
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown Scan verb %%%c", verb)
+	}
+	if err != nil {
+		return err
+	}
+	switch dst := dst.(type) {
+	case *string:
+		if dst != nil {
+			*dst = string(token)
+			return nil
+		}
+	case *[]byte:
+		if dst != nil {
+			*dst = []byte(token)
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot Scan %%%c in %T", verb, dst)
+}
+
+// storeInteger stores theValue, whose sign is given by negative, in the
+// integer dst points to.
+func (s *Parser[T]) storeInteger(mark ParserMark, dst any, theValue uint64, negative bool) error {
+	value := reflect.ValueOf(dst)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return fmt.Errorf("cannot Scan an integer in %T", dst)
+	}
+	value = value.Elem()
+	overflow := false
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		signed := int64(theValue)
+		if overflow = !negative && theValue > math.MaxInt64 || value.OverflowInt(signed); !overflow {
+			value.SetInt(signed)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if overflow = negative || value.OverflowUint(theValue); !overflow {
+			value.SetUint(theValue)
+		}
+	default:
+		return fmt.Errorf("cannot Scan an integer in %T", dst)
+	}
+	if overflow {
+		return s.rangeError(mark, "integer", fmt.Errorf("integer overflows %s: %w", value.Type(), strconv.ErrRange))
+	}
+	return nil
+}
+
+// storeFloat stores theFloat in the float64 or float32 dst points to.
+func (s *Parser[T]) storeFloat(mark ParserMark, dst any, theFloat float64) error {
+	switch dst := dst.(type) {
+	case *float64:
+		if dst != nil {
+			*dst = theFloat
+			return nil
+		}
+	case *float32:
+		if dst == nil {
+			break
+		}
+		if math.Abs(theFloat) > math.MaxFloat32 {
+			return s.rangeError(mark, "number", fmt.Errorf("number overflows float32: %w", strconv.ErrRange))
+		}
+		*dst = float32(theFloat)
+		return nil
+	}
+	return fmt.Errorf("cannot Scan a number in %T", dst)
+}
@@ -0,0 +1,553 @@
+package commonutilities
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SessionDescription is a parsed SDP session description, RFC 4566.
+type SessionDescription struct {
+	Version       int
+	Origin        Origin
+	SessionName   string
+	Information   string
+	URI           string
+	Emails        []string
+	Phones        []string
+	Connection    *Connection
+	Bandwidths    []Bandwidth
+	Timings       []Timing
+	TimeZones     string
+	EncryptionKey string
+	Attributes    []Attribute
+	Media         []MediaDescription
+}
+
+// Origin is the o= line.
+type Origin struct {
+	Username       string
+	SessionID      string
+	SessionVersion string
+	NetType        string
+	AddrType       string
+	Address        string
+}
+
+// Connection is a c= line, e.g. "IN IP4 224.2.36.42/127/3".
+type Connection struct {
+	NetType  string
+	AddrType string
+	Address  string
+	// TTL and NumAddresses are only set for multicast addresses
+	TTL          int
+	NumAddresses int
+}
+
+// Bandwidth is a b= line, e.g. "AS:64".
+type Bandwidth struct {
+	Type      string
+	Bandwidth int
+}
+
+// Timing is a t= line and the r= lines that follow it.
+type Timing struct {
+	Start   uint64
+	Stop    uint64
+	Repeats []string
+}
+
+// Attribute is an a= line. Value is empty for property attributes such as
+// "a=recvonly".
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// MediaDescription is an m= line and the lines that follow it.
+type MediaDescription struct {
+	Media         string
+	Port          int
+	PortCount     int
+	Proto         string
+	Formats       []string
+	Information   string
+	Connection    *Connection
+	Bandwidths    []Bandwidth
+	EncryptionKey string
+	Attributes    []Attribute
+}
+
+// RTPMap is an "a=rtpmap:96 H264/90000" attribute.
+type RTPMap struct {
+	PayloadType    int
+	EncodingName   string
+	ClockRate      int
+	EncodingParams string
+}
+
+// ParseSessionDescription parses SDP text. Lines may end in \r\n, \r or \n.
+// Errors are *ParseError values citing the line they were found on.
+func ParseSessionDescription(inString string) (*SessionDescription, error) {
+	parser := New(inString)
+	session := &SessionDescription{}
+	var media *MediaDescription
+	var sawVersion bool
+
+	for !parser.ParserIsEmpty() {
+		lineMark := parser.Mark()
+		line, _ := parser.GetThruEOL()
+		if line == "" {
+			continue
+		}
+		lineParser := New(line)
+		typeChar := lineParser.PeekFast()
+		lineParser.ConsumeLength(1)
+		if !lineParser.Expect('=') {
+			return nil, sdpError(parser, lineMark, 1, "'='", nil)
+		}
+		value := lineParser.ConsumeLength(lineParser.GetDataRemaining())
+		if !sawVersion && typeChar != 'v' {
+			return nil, sdpError(parser, lineMark, 0, "v= first", nil)
+		}
+		sawVersion = true
+
+		var err error
+		switch {
+		case typeChar == 'm':
+			session.Media = append(session.Media, MediaDescription{})
+			media = &session.Media[len(session.Media)-1]
+			err = media.parseMediaLine(value)
+		case typeChar == 'a' && media != nil:
+			media.Attributes = append(media.Attributes, parseAttribute(value))
+		case typeChar == 'a':
+			session.Attributes = append(session.Attributes, parseAttribute(value))
+		case typeChar == 'c' && media != nil:
+			media.Connection, err = parseConnection(value)
+		case typeChar == 'c':
+			session.Connection, err = parseConnection(value)
+		case typeChar == 'b' && media != nil:
+			err = appendBandwidth(&media.Bandwidths, value)
+		case typeChar == 'b':
+			err = appendBandwidth(&session.Bandwidths, value)
+		case typeChar == 'i' && media != nil:
+			media.Information = value
+		case typeChar == 'k' && media != nil:
+			media.EncryptionKey = value
+		case media != nil:
+			err = fmt.Errorf("%c= is not allowed in a media description", typeChar)
+		case typeChar == 'v':
+			session.Version, err = strconv.Atoi(value)
+		case typeChar == 'o':
+			err = session.Origin.parse(value)
+		case typeChar == 's':
+			session.SessionName = value
+		case typeChar == 'i':
+			session.Information = value
+		case typeChar == 'u':
+			session.URI = value
+		case typeChar == 'e':
+			session.Emails = append(session.Emails, value)
+		case typeChar == 'p':
+			session.Phones = append(session.Phones, value)
+		case typeChar == 't':
+			err = appendTiming(&session.Timings, value)
+		case typeChar == 'r':
+			if len(session.Timings) == 0 {
+				err = fmt.Errorf("r= before t=")
+				break
+			}
+			timing := &session.Timings[len(session.Timings)-1]
+			timing.Repeats = append(timing.Repeats, value)
+		case typeChar == 'z':
+			session.TimeZones = value
+		case typeChar == 'k':
+			session.EncryptionKey = value
+		}
+		if err != nil {
+			return nil, sdpError(parser, lineMark, 2, fmt.Sprintf("%c= value", typeChar), err)
+		}
+	}
+	if !sawVersion {
+		return nil, parser.NewParseError("v=")
+	}
+	return session, nil
+}
+
+// sdpError returns a ParseError at column skip+1 of the line at lineMark.
+func sdpError(parser *StringParser, lineMark ParserMark, skip int, expected string, err error) error {
+	parser.Reset(lineMark)
+	parser.ConsumeLength(skip)
+	parseError := parser.NewParseError(expected)
+	parseError.Err = err
+	return parseError
+}
+
+// Attribute returns the value of the first session level attribute called key.
+func (d *SessionDescription) Attribute(key string) (string, bool) {
+	return findAttribute(d.Attributes, key)
+}
+
+// Control returns the session level a=control attribute.
+func (d *SessionDescription) Control() string {
+	control, _ := d.Attribute("control")
+	return control
+}
+
+// Direction returns the session level sendrecv, sendonly, recvonly or
+// inactive attribute, "sendrecv" if there is none.
+func (d *SessionDescription) Direction() string {
+	if direction := findDirection(d.Attributes); direction != "" {
+		return direction
+	}
+	return "sendrecv"
+}
+
+// Attribute returns the value of the first attribute called key.
+func (m *MediaDescription) Attribute(key string) (string, bool) {
+	return findAttribute(m.Attributes, key)
+}
+
+// Control returns the a=control attribute, e.g. "streamid=0" or "track1".
+func (m *MediaDescription) Control() string {
+	control, _ := m.Attribute("control")
+	return control
+}
+
+// Direction returns the media level sendrecv, sendonly, recvonly or inactive
+// attribute, or "" if the media inherits the session's Direction.
+func (m *MediaDescription) Direction() string {
+	return findDirection(m.Attributes)
+}
+
+// FrameRate returns the a=framerate attribute of a video stream, e.g. 25 or
+// 29.97.
+func (m *MediaDescription) FrameRate() (float64, bool) {
+	value, ok := m.Attribute("framerate")
+	if !ok {
+		return 0, false
+	}
+	parser := New(strings.TrimSpace(value))
+	frameRate, err := parser.ConsumeFloatErr()
+	if err != nil || !parser.ParserIsEmpty() || frameRate <= 0 {
+		return 0, false
+	}
+	return frameRate, true
+}
+
+// RTPMaps returns every a=rtpmap attribute in order. Malformed ones are
+// skipped.
+func (m *MediaDescription) RTPMaps() (rtpMaps []RTPMap) {
+	for _, attribute := range m.Attributes {
+		if attribute.Key != "rtpmap" {
+			continue
+		}
+		if rtpMap, err := parseRTPMap(attribute.Value); err == nil {
+			rtpMaps = append(rtpMaps, rtpMap)
+		}
+	}
+	return
+}
+
+// RTPMap returns the a=rtpmap attribute for payloadType.
+func (m *MediaDescription) RTPMap(payloadType int) (RTPMap, bool) {
+	for _, rtpMap := range m.RTPMaps() {
+		if rtpMap.PayloadType == payloadType {
+			return rtpMap, true
+		}
+	}
+	return RTPMap{}, false
+}
+
+// Fmtp returns the parameters of the a=fmtp attribute for payloadType, e.g.
+// "packetization-mode=1; profile-level-id=42001F".
+func (m *MediaDescription) Fmtp(payloadType int) (string, bool) {
+	for _, attribute := range m.Attributes {
+		if attribute.Key != "fmtp" {
+			continue
+		}
+		parser := New(attribute.Value)
+		if digits, value := parser.ConsumeInteger(); digits != "" && int(value) == payloadType {
+			parser.ConsumeWhitespace()
+			return parser.ConsumeLength(parser.GetDataRemaining()), true
+		}
+	}
+	return "", false
+}
+
+func (m *MediaDescription) parseMediaLine(value string) (err error) {
+	fields := strings.Fields(value)
+	if len(fields) < 4 {
+		return fmt.Errorf("expected <media> <port> <proto> <fmt>")
+	}
+	m.Media, m.Proto, m.Formats = fields[0], fields[2], fields[3:]
+
+	parser := New(fields[1])
+	digits, port := parser.ConsumeInteger()
+	m.Port = int(port)
+	if digits != "" && parser.Expect('/') {
+		digits, count := parser.ConsumeInteger()
+		if digits == "" {
+			return fmt.Errorf("bad port %q", fields[1])
+		}
+		m.PortCount = int(count)
+	}
+	if digits == "" || !parser.ParserIsEmpty() {
+		return fmt.Errorf("bad port %q", fields[1])
+	}
+	return nil
+}
+
+func (o *Origin) parse(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) != 6 {
+		return fmt.Errorf("expected <username> <sess-id> <sess-version> <nettype> <addrtype> <unicast-address>")
+	}
+	*o = Origin{fields[0], fields[1], fields[2], fields[3], fields[4], fields[5]}
+	return nil
+}
+
+func parseConnection(value string) (*Connection, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("expected <nettype> <addrtype> <connection-address>")
+	}
+	connection := &Connection{NetType: fields[0], AddrType: fields[1]}
+
+	parser := New(fields[2])
+	connection.Address, _ = parser.GetThru('/')
+	var numbers []int
+	for !parser.ParserIsEmpty() {
+		digits, number := parser.ConsumeInteger()
+		if digits == "" || !(parser.Expect('/') || parser.ParserIsEmpty()) {
+			return nil, fmt.Errorf("bad address %q", fields[2])
+		}
+		numbers = append(numbers, int(number))
+	}
+	switch {
+	case len(numbers) > 2:
+		return nil, fmt.Errorf("bad address %q", fields[2])
+	case len(numbers) == 2:
+		connection.TTL, connection.NumAddresses = numbers[0], numbers[1]
+	case len(numbers) == 1 && connection.AddrType == "IP6":
+		connection.NumAddresses = numbers[0]
+	case len(numbers) == 1:
+		connection.TTL = numbers[0]
+	}
+	return connection, nil
+}
+
+func appendBandwidth(bandwidths *[]Bandwidth, value string) error {
+	parser := New(value)
+	bwType, ok := parser.GetThru(':')
+	digits, bandwidth := parser.ConsumeInteger()
+	if !ok || bwType == "" || digits == "" || !parser.ParserIsEmpty() {
+		return fmt.Errorf("expected <bwtype>:<bandwidth>")
+	}
+	*bandwidths = append(*bandwidths, Bandwidth{Type: bwType, Bandwidth: int(bandwidth)})
+	return nil
+}
+
+func appendTiming(timings *[]Timing, value string) error {
+	var timing Timing
+	parser := New(strings.TrimSpace(value))
+	if err := parser.Scan("%d %d", &timing.Start, &timing.Stop); err != nil || !parser.ParserIsEmpty() {
+		return fmt.Errorf("expected <start-time> <stop-time>")
+	}
+	*timings = append(*timings, timing)
+	return nil
+}
+
+func parseAttribute(value string) Attribute {
+	parser := New(value)
+	if key, ok := parser.GetThru(':'); ok {
+		return Attribute{Key: key, Value: parser.ConsumeLength(parser.GetDataRemaining())}
+	}
+	return Attribute{Key: value}
+}
+
+func parseRTPMap(value string) (rtpMap RTPMap, err error) {
+	parser := New(value)
+	digits, payloadType := parser.ConsumeInteger()
+	parser.ConsumeWhitespace()
+	encodingName, ok := parser.GetThru('/')
+	clockDigits, clockRate := parser.ConsumeInteger()
+	if digits == "" || !ok || encodingName == "" || clockDigits == "" {
+		return rtpMap, fmt.Errorf("malformed rtpmap %q", value)
+	}
+	rtpMap = RTPMap{PayloadType: int(payloadType), EncodingName: encodingName, ClockRate: int(clockRate)}
+	if parser.Expect('/') {
+		rtpMap.EncodingParams = parser.ConsumeLength(parser.GetDataRemaining())
+	}
+	return rtpMap, nil
+}
+
+func findAttribute(attributes []Attribute, key string) (string, bool) {
+	for _, attribute := range attributes {
+		if attribute.Key == key {
+			return attribute.Value, true
+		}
+	}
+	return "", false
+}
+
+func findDirection(attributes []Attribute) string {
+	for _, attribute := range attributes {
+		switch attribute.Key {
+		case "sendrecv", "sendonly", "recvonly", "inactive":
+			return attribute.Key
+		}
+	}
+	return ""
+}
+
+// String writes the session description in the order RFC 4566 requires,
+// each line ending in \r\n. The mandatory o=, s= and t= lines are filled in
+// with "-", "0", "IN", "IP4", "0.0.0.0" or "0 0" where they are missing.
+func (d *SessionDescription) String() string {
+	var b strings.Builder
+	writeLine := func(typeChar byte, value string) {
+		b.WriteByte(typeChar)
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteString("\r\n")
+	}
+
+	writeLine('v', strconv.Itoa(d.Version))
+	writeLine('o', d.Origin.String())
+	sessionName := d.SessionName
+	if sessionName == "" {
+		sessionName = "-"
+	}
+	writeLine('s', sessionName)
+	if d.Information != "" {
+		writeLine('i', d.Information)
+	}
+	if d.URI != "" {
+		writeLine('u', d.URI)
+	}
+	for _, email := range d.Emails {
+		writeLine('e', email)
+	}
+	for _, phone := range d.Phones {
+		writeLine('p', phone)
+	}
+	if d.Connection != nil {
+		writeLine('c', d.Connection.String())
+	}
+	for _, bandwidth := range d.Bandwidths {
+		writeLine('b', bandwidth.String())
+	}
+	timings := d.Timings
+	if len(timings) == 0 {
+		timings = []Timing{{}}
+	}
+	for _, timing := range timings {
+		writeLine('t', strconv.FormatUint(timing.Start, 10)+" "+strconv.FormatUint(timing.Stop, 10))
+		for _, repeat := range timing.Repeats {
+			writeLine('r', repeat)
+		}
+	}
+	if d.TimeZones != "" {
+		writeLine('z', d.TimeZones)
+	}
+	if d.EncryptionKey != "" {
+		writeLine('k', d.EncryptionKey)
+	}
+	for _, attribute := range d.Attributes {
+		writeLine('a', attribute.String())
+	}
+
+	for _, media := range d.Media {
+		port := strconv.Itoa(media.Port)
+		if media.PortCount != 0 {
+			port += "/" + strconv.Itoa(media.PortCount)
+		}
+		writeLine('m', strings.Join(append([]string{media.Media, port, media.Proto}, media.Formats...), " "))
+		if media.Information != "" {
+			writeLine('i', media.Information)
+		}
+		if media.Connection != nil {
+			writeLine('c', media.Connection.String())
+		}
+		for _, bandwidth := range media.Bandwidths {
+			writeLine('b', bandwidth.String())
+		}
+		if media.EncryptionKey != "" {
+			writeLine('k', media.EncryptionKey)
+		}
+		for _, attribute := range media.Attributes {
+			writeLine('a', attribute.String())
+		}
+	}
+	return b.String()
+}
+
+// String returns the value of the o= line.
+func (o Origin) String() string {
+	fields := []string{o.Username, o.SessionID, o.SessionVersion, o.NetType, o.AddrType, o.Address}
+	for i, field := range fields {
+		if field == "" {
+			fields[i] = [...]string{"-", "0", "0", "IN", "IP4", "0.0.0.0"}[i]
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// String returns the value of the c= line.
+func (c Connection) String() string {
+	address := c.Address
+	if c.TTL != 0 {
+		address += "/" + strconv.Itoa(c.TTL)
+	}
+	if c.NumAddresses != 0 {
+		address += "/" + strconv.Itoa(c.NumAddresses)
+	}
+	return c.NetType + " " + c.AddrType + " " + address
+}
+
+// String returns the value of the b= line.
+func (b Bandwidth) String() string {
+	return b.Type + ":" + strconv.Itoa(b.Bandwidth)
+}
+
+// String returns the value of the a= line.
+func (a Attribute) String() string {
+	if a.Value == "" {
+		return a.Key
+	}
+	return a.Key + ":" + a.Value
+}
+
+// String returns the value of the rtpmap attribute, e.g. "96 H264/90000".
+func (r RTPMap) String() string {
+	rtpMap := strconv.Itoa(r.PayloadType) + " " + r.EncodingName + "/" + strconv.Itoa(r.ClockRate)
+	if r.EncodingParams != "" {
+		rtpMap += "/" + r.EncodingParams
+	}
+	return rtpMap
+}
+
+// AddAttribute appends a session level a= line.
+func (d *SessionDescription) AddAttribute(key, value string) {
+	d.Attributes = append(d.Attributes, Attribute{Key: key, Value: value})
+}
+
+// AddAttribute appends an a= line to the media description.
+func (m *MediaDescription) AddAttribute(key, value string) {
+	m.Attributes = append(m.Attributes, Attribute{Key: key, Value: value})
+}
+
+// AddRTPMap appends an a=rtpmap line and adds the payload type to Formats if
+// it is not there yet.
+func (m *MediaDescription) AddRTPMap(rtpMap RTPMap) {
+	payloadType := strconv.Itoa(rtpMap.PayloadType)
+	found := false
+	for _, format := range m.Formats {
+		found = found || format == payloadType
+	}
+	if !found {
+		m.Formats = append(m.Formats, payloadType)
+	}
+	m.AddAttribute("rtpmap", rtpMap.String())
+}
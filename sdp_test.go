@@ -0,0 +1,201 @@
+package commonutilities
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestParseSessionDescription(t *testing.T) {
+	request, _ := ParseRequest(announceRequest)
+	for _, body := range []string{request.Body, strings.Replace(request.Body, "\r\n", "\n", -1)} {
+		session, err := ParseSessionDescription(body)
+		if err != nil {
+			t.Fatalf("ParseSessionDescription(%q) error %v", body, err)
+		}
+		if session.Origin != (Origin{"-", "0", "0", "IN", "IP4", "127.0.0.1"}) || session.SessionName != "RTSP Session" {
+			t.Errorf("ParseSessionDescription(%q) = %+v", body, session)
+		}
+		if session.Connection == nil || session.Connection.Address != "192.168.199.136" {
+			t.Errorf("ParseSessionDescription(%q) Connection = %+v", body, session.Connection)
+		}
+		if len(session.Timings) != 1 || session.Timings[0].Start != 0 || session.Timings[0].Stop != 0 {
+			t.Errorf("ParseSessionDescription(%q) Timings = %+v", body, session.Timings)
+		}
+		if tool, _ := session.Attribute("tool"); tool != "libavformat 57.83.100" || session.Direction() != "sendrecv" {
+			t.Errorf("ParseSessionDescription(%q) Attributes = %+v", body, session.Attributes)
+		}
+		if len(session.Media) != 2 {
+			t.Fatalf("ParseSessionDescription(%q) Media = %+v", body, session.Media)
+		}
+
+		video, audio := session.Media[0], session.Media[1]
+		if video.Media != "video" || video.Proto != "RTP/AVP" || len(video.Formats) != 1 || video.Formats[0] != "96" {
+			t.Errorf("ParseSessionDescription(%q) Media[0] = %+v", body, video)
+		}
+		if rtpMap, ok := video.RTPMap(96); !ok || rtpMap != (RTPMap{96, "H264", 90000, ""}) {
+			t.Errorf("RTPMap(96) = %+v, %v", rtpMap, ok)
+		}
+		if fmtp, _ := video.Fmtp(96); !strings.HasPrefix(fmtp, "packetization-mode=1; sprop-parameter-sets=") {
+			t.Errorf("Fmtp(96) = %q", fmtp)
+		}
+		if video.Control() != "streamid=0" || audio.Control() != "streamid=1" {
+			t.Errorf("Control() = %q, %q", video.Control(), audio.Control())
+		}
+		if audio.Formats[0] != "8" || len(audio.Bandwidths) != 1 || audio.Bandwidths[0] != (Bandwidth{"AS", 64}) {
+			t.Errorf("ParseSessionDescription(%q) Media[1] = %+v", body, audio)
+		}
+		if _, ok := audio.RTPMap(8); ok {
+			t.Errorf("RTPMap(8) found in %+v", audio)
+		}
+	}
+}
+
+func TestParseSessionDescriptionAttributes(t *testing.T) {
+	const body = "v=0\n" +
+		"o=alice 2890844526 2890842807 IN IP4 10.47.16.5\n" +
+		"s=-\n" +
+		"c=IN IP4 224.2.17.12/127\n" +
+		"t=2873397496 2873404696\n" +
+		"r=7d 1h 0 25h\n" +
+		"a=recvonly\n" +
+		"m=audio 49170/2 RTP/AVP 0 97\n" +
+		"a=rtpmap:97 opus/48000/2\n" +
+		"a=sendonly\n" +
+		"m=video 51372 RTP/AVP 31\n" +
+		"c=IN IP6 FF15::101/3\n" +
+		"a=framerate:29.97\n"
+	session, err := ParseSessionDescription(body)
+	if err != nil {
+		t.Fatalf("ParseSessionDescription(%q) error %v", body, err)
+	}
+	if session.Connection.TTL != 127 || session.Direction() != "recvonly" {
+		t.Errorf("ParseSessionDescription(%q) = %+v", body, session)
+	}
+	if got := session.Timings[0]; got.Start != 2873397496 || len(got.Repeats) != 1 {
+		t.Errorf("ParseSessionDescription(%q) Timings = %+v", body, session.Timings)
+	}
+	audio, video := session.Media[0], session.Media[1]
+	if audio.Port != 49170 || audio.PortCount != 2 || audio.Direction() != "sendonly" || video.Direction() != "" {
+		t.Errorf("ParseSessionDescription(%q) Media = %+v", body, session.Media)
+	}
+	if rtpMap, _ := audio.RTPMap(97); rtpMap.EncodingName != "opus" || rtpMap.EncodingParams != "2" {
+		t.Errorf("RTPMap(97) = %+v", rtpMap)
+	}
+	if video.Connection.NumAddresses != 3 || video.Connection.Address != "FF15::101" {
+		t.Errorf("ParseSessionDescription(%q) Media[1].Connection = %+v", body, video.Connection)
+	}
+	if frameRate, ok := video.FrameRate(); frameRate != 29.97 || !ok {
+		t.Errorf("FrameRate() = %v, %v", frameRate, ok)
+	}
+	if _, ok := audio.FrameRate(); ok {
+		t.Errorf("FrameRate() of audio succeeded")
+	}
+
+	var tests = []struct {
+		input string
+		want  string
+	}{
+		{"", "line 1, column 1: expected v="},
+		{"s=-\r\nv=0\r\n", "line 1, column 1: expected v= first near \"s=-\""},
+		{"v=0\r\nb:AS\r\n", "line 2, column 2: expected '=' near \"b:AS\""},
+		{"v=0\r\nt=0\r\n", "line 2, column 3: expected <start-time> <stop-time> near \"t=0\""},
+		{"v=0\r\nm=video 0 RTP/AVP\r\n", "line 2, column 3: expected <media> <port> <proto> <fmt> near \"m=video 0 RTP/AVP\""},
+		{"v=0\r\nm=video x RTP/AVP 96\r\n", "line 2, column 3: bad port \"x\" near \"m=video x RTP/AVP 96\""},
+		{"v=0\r\nm=video 0 RTP/AVP 96\r\ns=late\r\n", "line 3, column 3: s= is not allowed in a media description near \"s=late\""},
+	}
+	for _, test := range tests {
+		if _, err := ParseSessionDescription(test.input); err == nil || err.Error() != test.want {
+			t.Errorf("ParseSessionDescription(%q) error %v", test.input, err)
+		}
+	}
+}
+
+func TestSessionDescriptionString(t *testing.T) {
+	request, _ := ParseRequest(announceRequest)
+	session, _ := ParseSessionDescription(request.Body)
+	want := request.Body
+	if got := session.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	again, err := ParseSessionDescription(session.String())
+	if err != nil || again.String() != want {
+		t.Errorf("ParseSessionDescription(String()) = %v, %v", again, err)
+	}
+
+	var built SessionDescription
+	built.Media = append(built.Media, MediaDescription{Media: "video", Proto: "RTP/AVP"})
+	built.Media[0].AddRTPMap(RTPMap{PayloadType: 96, EncodingName: "H264", ClockRate: 90000})
+	built.Media[0].AddAttribute("control", "track1")
+	built.AddAttribute("control", "*")
+	built.Connection = &Connection{NetType: "IN", AddrType: "IP4", Address: "0.0.0.0"}
+	want = "v=0\r\n" +
+		"o=- 0 0 IN IP4 0.0.0.0\r\n" +
+		"s=-\r\n" +
+		"c=IN IP4 0.0.0.0\r\n" +
+		"t=0 0\r\n" +
+		"a=control:*\r\n" +
+		"m=video 0 RTP/AVP 96\r\n" +
+		"a=rtpmap:96 H264/90000\r\n" +
+		"a=control:track1\r\n"
+	if got := built.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestParseFmtp(t *testing.T) {
+	request, _ := ParseRequest(announceRequest)
+	session, _ := ParseSessionDescription(request.Body)
+	fmtp, err := session.Media[0].ParsedFmtp(96)
+	if err != nil {
+		t.Fatalf("ParsedFmtp(96) error %v", err)
+	}
+	if mode, _ := fmtp.Get("Packetization-Mode"); fmtp.PayloadType != 96 || len(fmtp.Params) != 3 || mode != "1" {
+		t.Errorf("ParsedFmtp(96) = %+v", fmtp)
+	}
+	if got := fmtp.Map()["sprop-parameter-sets"]; got != "Z0IAH52oFAFum4CAgIE=,aM48gA==" {
+		t.Errorf("Map()[sprop-parameter-sets] = %q", got)
+	}
+
+	sps, pps, err := fmtp.H264ParameterSets()
+	if err != nil || len(sps) != 1 || len(pps) != 1 {
+		t.Fatalf("H264ParameterSets() = %x, %x, %v", sps, pps, err)
+	}
+	if hex.EncodeToString(sps[0]) != "6742001f9da814016e9b80808081" || hex.EncodeToString(pps[0]) != "68ce3c80" {
+		t.Errorf("H264ParameterSets() = %x, %x", sps, pps)
+	}
+	profileLevel, err := fmtp.H264ProfileLevel()
+	if err != nil || profileLevel != (H264ProfileLevel{66, 0, 31}) || profileLevel.Level() != "3.1" {
+		t.Errorf("H264ProfileLevel() = %+v, %v", profileLevel, err)
+	}
+
+	fmtp, err = ParseFmtp("98 profile-id=1;sprop-vps=QAEMAf//AWAAAAMAkAAAAwAAAwBdlZgJ;sprop-sps=QgEBAWAAAAMAkAAAAwAAAwBdoAKAgC0WWVmkkyvAQAAA+kAAF3AC;sprop-pps=RAHBcrRiQA")
+	if err != nil {
+		t.Fatalf("ParseFmtp() error %v", err)
+	}
+	vps, sps, pps, err := fmtp.H265ParameterSets()
+	if err != nil || len(vps) != 1 || len(sps) != 1 || len(pps) != 1 || vps[0][0]>>1 != 32 || sps[0][0]>>1 != 33 || pps[0][0]>>1 != 34 {
+		t.Errorf("H265ParameterSets() = %x, %x, %x, %v", vps, sps, pps, err)
+	}
+	if level := (H264ProfileLevel{66, 0x10, 11}).Level(); level != "1b" {
+		t.Errorf("Level() = %q", level)
+	}
+
+	fmtp, err = ParseFmtp(`97 mode="1;2" ; config="a\"b";x`)
+	if err != nil || len(fmtp.Params) != 3 || fmtp.Params[0].Value != "1;2" || fmtp.Params[1].Value != `a"b` || fmtp.Params[2].Name != "x" {
+		t.Errorf("ParseFmtp() = %+v, %v", fmtp, err)
+	}
+
+	for _, input := range []string{"", "x a=b", "96a=b", "96 =b", `96 a="b`, `96 a="b"c`} {
+		if fmtp, err := ParseFmtp(input); err == nil {
+			t.Errorf("ParseFmtp(%q) = %+v", input, fmtp)
+		}
+	}
+	fmtp, _ = ParseFmtp("96 sprop-parameter-sets=@@@;profile-level-id=42")
+	if _, _, err := fmtp.H264ParameterSets(); err == nil {
+		t.Error("H264ParameterSets() decoded @@@")
+	}
+	if _, err := fmtp.H264ProfileLevel(); err == nil {
+		t.Error("H264ProfileLevel() decoded 42")
+	}
+}
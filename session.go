@@ -0,0 +1,52 @@
+package commonutilities
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// DefaultSessionTimeout is the session timeout in seconds a server applies
+// when its Session header does not give one, RFC 2326 section 12.37.
+const DefaultSessionTimeout = 60
+
+// Session is a parsed Session header such as "E1155C20;timeout=60".
+type Session struct {
+	ID string
+	// Timeout is in seconds, zero when the header did not give one
+	Timeout int
+}
+
+// ParseSession parses a Session header. Unknown parameters are ignored.
+func ParseSession(value string) (session Session, err error) {
+	elements, err := ParseHeaderValue(value)
+	if err != nil {
+		return session, fmt.Errorf("malformed Session %q: %w", value, err)
+	}
+	if len(elements) != 1 || elements[0].Token == "" {
+		return session, fmt.Errorf("malformed Session %q", value)
+	}
+	session.ID = elements[0].Token
+	if timeout, ok := elements[0].Param("timeout"); ok {
+		if session.Timeout, err = strconv.Atoi(timeout); err != nil || session.Timeout <= 0 {
+			return session, fmt.Errorf("malformed Session timeout %q", timeout)
+		}
+	}
+	return session, nil
+}
+
+// TimeoutOrDefault returns Timeout, or DefaultSessionTimeout if it is zero.
+func (s Session) TimeoutOrDefault() int {
+	if s.Timeout == 0 {
+		return DefaultSessionTimeout
+	}
+	return s.Timeout
+}
+
+// String formats the Session header.
+func (s Session) String() string {
+	session := HeaderElement{Token: s.ID}
+	if s.Timeout != 0 {
+		session.AddParam("timeout", strconv.Itoa(s.Timeout))
+	}
+	return session.String()
+}
@@ -0,0 +1,438 @@
+package commonutilities
+
+import (
+	"fmt"
+)
+
+// H264SPS holds the fields of an H.264 sequence parameter set needed to
+// describe a stream, ITU-T H.264 section 7.3.2.1.1.
+type H264SPS struct {
+	ProfileIDC uint8
+	// ConstraintFlags holds constraint_set0_flag in its most significant bit
+	ConstraintFlags uint8
+	LevelIDC        uint8
+	ID              uint32
+	// ChromaFormatIDC is 1 (4:2:0) unless a high profile says otherwise
+	ChromaFormatIDC uint32
+	BitDepthLuma    uint32
+	BitDepthChroma  uint32
+	FrameMBSOnly    bool
+	// Width and Height are in pixels after cropping
+	Width  int
+	Height int
+	// CropLeft, CropRight, CropTop and CropBottom are in pixels
+	CropLeft   int
+	CropRight  int
+	CropTop    int
+	CropBottom int
+	// SARWidth and SARHeight are the sample aspect ratio, 0 if unknown
+	SARWidth  uint16
+	SARHeight uint16
+	// NumUnitsInTick and TimeScale are set when TimingInfoPresent
+	TimingInfoPresent bool
+	NumUnitsInTick    uint32
+	TimeScale         uint32
+	FixedFrameRate    bool
+}
+
+// H265SPS holds the fields of an H.265 sequence parameter set needed to
+// describe a stream, ITU-T H.265 section 7.3.2.2.
+type H265SPS struct {
+	ProfileSpace uint8
+	TierFlag     bool
+	ProfileIDC   uint8
+	// ProfileCompatibilityFlags holds general_profile_compatibility_flag[0]
+	// in its most significant bit
+	ProfileCompatibilityFlags uint32
+	LevelIDC                  uint8
+	ID                        uint32
+	ChromaFormatIDC           uint32
+	BitDepthLuma              uint32
+	BitDepthChroma            uint32
+	// Width and Height are in pixels after the conformance window cropping
+	Width  int
+	Height int
+	// CropLeft, CropRight, CropTop and CropBottom are in pixels
+	CropLeft   int
+	CropRight  int
+	CropTop    int
+	CropBottom int
+	// SARWidth and SARHeight are the sample aspect ratio, 0 if unknown
+	SARWidth  uint16
+	SARHeight uint16
+	// NumUnitsInTick and TimeScale are set when TimingInfoPresent
+	TimingInfoPresent bool
+	NumUnitsInTick    uint32
+	TimeScale         uint32
+}
+
+// aspect_ratio_idc value followed by an explicit sar_width and sar_height
+const extendedSAR = 255
+
+// sample aspect ratios of aspect_ratio_idc 1 to 16, Table E-1
+var sampleAspectRatios = [][2]uint16{
+	{1, 1}, {12, 11}, {10, 11}, {16, 11}, {40, 33}, {24, 11}, {20, 11}, {32, 11},
+	{80, 33}, {18, 11}, {15, 11}, {64, 33}, {160, 99}, {4, 3}, {3, 2}, {2, 1},
+}
+
+// H.264 profiles whose SPS carries chroma_format_idc and bit depths
+var h264HighProfiles = map[uint8]bool{
+	100: true, 110: true, 122: true, 244: true, 44: true, 83: true, 86: true,
+	118: true, 128: true, 138: true, 139: true, 134: true, 135: true,
+}
+
+// ParseH264SPS decodes an H.264 SPS NAL unit, including its one byte NAL
+// unit header, as found in sprop-parameter-sets.
+func ParseH264SPS(nalUnit []byte) (*H264SPS, error) {
+	if len(nalUnit) < 4 || nalUnit[0]&0x1f != h264NALTypeSPS {
+		return nil, fmt.Errorf("not an H.264 SPS")
+	}
+	r := NewBitReader(RemoveEmulationPrevention(nalUnit[1:]))
+	sps := &H264SPS{ChromaFormatIDC: 1, BitDepthLuma: 8, BitDepthChroma: 8}
+	sps.ProfileIDC = r.ReadUint8()
+	sps.ConstraintFlags = r.ReadUint8()
+	sps.LevelIDC = r.ReadUint8()
+	sps.ID = r.ReadUE()
+
+	separateColourPlane := false
+	if h264HighProfiles[sps.ProfileIDC] {
+		if sps.ChromaFormatIDC = r.ReadUE(); sps.ChromaFormatIDC == 3 {
+			separateColourPlane = r.ReadFlag()
+		}
+		sps.BitDepthLuma = r.ReadUE() + 8
+		sps.BitDepthChroma = r.ReadUE() + 8
+		r.SkipBits(1)     // qpprime_y_zero_transform_bypass_flag
+		if r.ReadFlag() { // seq_scaling_matrix_present_flag
+			lists := 8
+			if sps.ChromaFormatIDC == 3 {
+				lists = 12
+			}
+			for i := 0; i < lists; i++ {
+				if !r.ReadFlag() {
+					continue
+				}
+				size := 64
+				if i < 6 {
+					size = 16
+				}
+				skipH264ScalingList(r, size)
+			}
+		}
+	}
+
+	r.ReadUE()          // log2_max_frame_num_minus4
+	switch r.ReadUE() { // pic_order_cnt_type
+	case 0:
+		r.ReadUE() // log2_max_pic_order_cnt_lsb_minus4
+	case 1:
+		r.SkipBits(1) // delta_pic_order_always_zero_flag
+		r.ReadSE()    // offset_for_non_ref_pic
+		r.ReadSE()    // offset_for_top_to_bottom_field
+		for i := r.ReadUE(); i > 0 && r.Err() == nil; i-- {
+			r.ReadSE() // offset_for_ref_frame
+		}
+	}
+	r.ReadUE()    // max_num_ref_frames
+	r.SkipBits(1) // gaps_in_frame_num_value_allowed_flag
+	widthInMBs := int(r.ReadUE()) + 1
+	heightInMapUnits := int(r.ReadUE()) + 1
+	sps.FrameMBSOnly = r.ReadFlag()
+	if !sps.FrameMBSOnly {
+		r.SkipBits(1) // mb_adaptive_frame_field_flag
+	}
+	r.SkipBits(1) // direct_8x8_inference_flag
+
+	// crop offsets are in chroma samples, and in field pairs for interlaced video
+	frameHeightFactor := 2
+	if sps.FrameMBSOnly {
+		frameHeightFactor = 1
+	}
+	cropUnitX, cropUnitY := 1, frameHeightFactor
+	if !separateColourPlane {
+		subWidth, subHeight := chromaSubsampling(sps.ChromaFormatIDC)
+		cropUnitX, cropUnitY = subWidth, subHeight*frameHeightFactor
+	}
+	if r.ReadFlag() { // frame_cropping_flag
+		sps.CropLeft = int(r.ReadUE()) * cropUnitX
+		sps.CropRight = int(r.ReadUE()) * cropUnitX
+		sps.CropTop = int(r.ReadUE()) * cropUnitY
+		sps.CropBottom = int(r.ReadUE()) * cropUnitY
+	}
+	sps.Width = widthInMBs*16 - sps.CropLeft - sps.CropRight
+	sps.Height = heightInMapUnits*16*frameHeightFactor - sps.CropTop - sps.CropBottom
+
+	if r.ReadFlag() { // vui_parameters_present_flag
+		sps.SARWidth, sps.SARHeight = readVUIVideoParameters(r)
+		if sps.TimingInfoPresent = r.ReadFlag(); sps.TimingInfoPresent {
+			sps.NumUnitsInTick = r.ReadUint32()
+			sps.TimeScale = r.ReadUint32()
+			sps.FixedFrameRate = r.ReadFlag()
+		}
+	}
+
+	if r.Err() != nil {
+		return nil, fmt.Errorf("malformed H.264 SPS: %v", r.Err())
+	}
+	if sps.Width <= 0 || sps.Height <= 0 {
+		return nil, fmt.Errorf("malformed H.264 SPS: %dx%d", sps.Width, sps.Height)
+	}
+	return sps, nil
+}
+
+func skipH264ScalingList(r *BitReader, size int) {
+	lastScale, nextScale := int32(8), int32(8)
+	for j := 0; j < size && r.Err() == nil; j++ {
+		if nextScale != 0 {
+			nextScale = (lastScale + r.ReadSE() + 256) % 256
+		}
+		if nextScale != 0 {
+			lastScale = nextScale
+		}
+	}
+}
+
+// chromaSubsampling returns SubWidthC and SubHeightC, Table 6-1.
+func chromaSubsampling(chromaFormatIDC uint32) (subWidth, subHeight int) {
+	switch chromaFormatIDC {
+	case 1:
+		return 2, 2
+	case 2:
+		return 2, 1
+	}
+	return 1, 1
+}
+
+// readVUIVideoParameters reads the VUI fields that come before the timing
+// info and are the same in H.264 and H.265, and returns the sample aspect
+// ratio.
+func readVUIVideoParameters(r *BitReader) (sarWidth, sarHeight uint16) {
+	if r.ReadFlag() { // aspect_ratio_info_present_flag
+		aspectRatioIDC := int(r.ReadUint8())
+		if aspectRatioIDC == extendedSAR {
+			sarWidth = r.ReadUint16()
+			sarHeight = r.ReadUint16()
+		} else if aspectRatioIDC >= 1 && aspectRatioIDC <= len(sampleAspectRatios) {
+			sarWidth = sampleAspectRatios[aspectRatioIDC-1][0]
+			sarHeight = sampleAspectRatios[aspectRatioIDC-1][1]
+		}
+	}
+	if r.ReadFlag() { // overscan_info_present_flag
+		r.SkipBits(1) // overscan_appropriate_flag
+	}
+	if r.ReadFlag() { // video_signal_type_present_flag
+		r.SkipBits(4)     // video_format, video_full_range_flag
+		if r.ReadFlag() { // colour_description_present_flag
+			r.SkipBits(24) // colour_primaries, transfer_characteristics, matrix_coeffs
+		}
+	}
+	if r.ReadFlag() { // chroma_loc_info_present_flag
+		r.ReadUE() // chroma_sample_loc_type_top_field
+		r.ReadUE() // chroma_sample_loc_type_bottom_field
+	}
+	return sarWidth, sarHeight
+}
+
+// FrameRate returns the frame rate given by the VUI timing info, or 0 if
+// there is none. Each frame is two ticks.
+func (sps *H264SPS) FrameRate() float64 {
+	if !sps.TimingInfoPresent || sps.NumUnitsInTick == 0 {
+		return 0
+	}
+	return float64(sps.TimeScale) / float64(2*sps.NumUnitsInTick)
+}
+
+// Level returns the level as written in the specification, e.g. 3.1.
+func (sps *H264SPS) Level() string {
+	return H264ProfileLevel{ProfileIDC: sps.ProfileIDC, ConstraintFlags: sps.ConstraintFlags, LevelIDC: sps.LevelIDC}.Level()
+}
+
+// ParseH265SPS decodes an H.265 SPS NAL unit, including its two byte NAL
+// unit header, as found in sprop-sps.
+func ParseH265SPS(nalUnit []byte) (*H265SPS, error) {
+	if len(nalUnit) < 4 || (nalUnit[0]>>1)&0x3f != h265NALTypeSPS {
+		return nil, fmt.Errorf("not an H.265 SPS")
+	}
+	r := NewBitReader(RemoveEmulationPrevention(nalUnit[2:]))
+	sps := &H265SPS{}
+	r.SkipBits(4) // sps_video_parameter_set_id
+	maxSubLayersMinus1 := int(r.ReadBits(3))
+	r.SkipBits(1) // sps_temporal_id_nesting_flag
+
+	// profile_tier_level, section 7.3.3
+	sps.ProfileSpace = uint8(r.ReadBits(2))
+	sps.TierFlag = r.ReadFlag()
+	sps.ProfileIDC = uint8(r.ReadBits(5))
+	sps.ProfileCompatibilityFlags = r.ReadUint32()
+	r.SkipBits(48) // source flags and constraint flags
+	sps.LevelIDC = r.ReadUint8()
+	subLayerProfilePresent := make([]bool, maxSubLayersMinus1)
+	subLayerLevelPresent := make([]bool, maxSubLayersMinus1)
+	for i := 0; i < maxSubLayersMinus1; i++ {
+		subLayerProfilePresent[i] = r.ReadFlag()
+		subLayerLevelPresent[i] = r.ReadFlag()
+	}
+	if maxSubLayersMinus1 > 0 {
+		r.SkipBits(2 * (8 - maxSubLayersMinus1)) // reserved_zero_2bits
+	}
+	for i := 0; i < maxSubLayersMinus1; i++ {
+		if subLayerProfilePresent[i] {
+			r.SkipBits(88)
+		}
+		if subLayerLevelPresent[i] {
+			r.SkipBits(8)
+		}
+	}
+
+	sps.ID = r.ReadUE()
+	separateColourPlane := false
+	if sps.ChromaFormatIDC = r.ReadUE(); sps.ChromaFormatIDC == 3 {
+		separateColourPlane = r.ReadFlag()
+	}
+	width := int(r.ReadUE())
+	height := int(r.ReadUE())
+	if r.ReadFlag() { // conformance_window_flag
+		cropUnitX, cropUnitY := 1, 1
+		if !separateColourPlane {
+			cropUnitX, cropUnitY = chromaSubsampling(sps.ChromaFormatIDC)
+		}
+		sps.CropLeft = int(r.ReadUE()) * cropUnitX
+		sps.CropRight = int(r.ReadUE()) * cropUnitX
+		sps.CropTop = int(r.ReadUE()) * cropUnitY
+		sps.CropBottom = int(r.ReadUE()) * cropUnitY
+	}
+	sps.Width = width - sps.CropLeft - sps.CropRight
+	sps.Height = height - sps.CropTop - sps.CropBottom
+	sps.BitDepthLuma = r.ReadUE() + 8
+	sps.BitDepthChroma = r.ReadUE() + 8
+	log2MaxPicOrderCntLsb := int(r.ReadUE()) + 4
+
+	subLayerOrderingInfoPresent := r.ReadFlag()
+	for i := 0; i <= maxSubLayersMinus1; i++ {
+		if subLayerOrderingInfoPresent || i == maxSubLayersMinus1 {
+			r.ReadUE() // sps_max_dec_pic_buffering_minus1
+			r.ReadUE() // sps_max_num_reorder_pics
+			r.ReadUE() // sps_max_latency_increase_plus1
+		}
+	}
+	for i := 0; i < 6; i++ {
+		r.ReadUE() // coding block, transform block and hierarchy depth sizes
+	}
+	if r.ReadFlag() && r.ReadFlag() { // scaling_list_enabled_flag, sps_scaling_list_data_present_flag
+		skipH265ScalingListData(r)
+	}
+	r.SkipBits(2)     // amp_enabled_flag, sample_adaptive_offset_enabled_flag
+	if r.ReadFlag() { // pcm_enabled_flag
+		r.SkipBits(8) // pcm_sample_bit_depth_luma_minus1, pcm_sample_bit_depth_chroma_minus1
+		r.ReadUE()    // log2_min_pcm_luma_coding_block_size_minus3
+		r.ReadUE()    // log2_diff_max_min_pcm_luma_coding_block_size
+		r.SkipBits(1) // pcm_loop_filter_disabled_flag
+	}
+	skipH265ShortTermRefPicSets(r)
+	if r.ReadFlag() { // long_term_ref_pics_present_flag
+		for i := r.ReadUE(); i > 0 && r.Err() == nil; i-- {
+			r.SkipBits(log2MaxPicOrderCntLsb + 1) // lt_ref_pic_poc_lsb_sps, used_by_curr_pic_lt_sps_flag
+		}
+	}
+	r.SkipBits(2) // sps_temporal_mvp_enabled_flag, strong_intra_smoothing_enabled_flag
+
+	if r.ReadFlag() { // vui_parameters_present_flag
+		sps.SARWidth, sps.SARHeight = readVUIVideoParameters(r)
+		r.SkipBits(3)     // neutral_chroma_indication_flag, field_seq_flag, frame_field_info_present_flag
+		if r.ReadFlag() { // default_display_window_flag
+			for i := 0; i < 4; i++ {
+				r.ReadUE()
+			}
+		}
+		if sps.TimingInfoPresent = r.ReadFlag(); sps.TimingInfoPresent {
+			sps.NumUnitsInTick = r.ReadUint32()
+			sps.TimeScale = r.ReadUint32()
+		}
+	}
+
+	if r.Err() != nil {
+		return nil, fmt.Errorf("malformed H.265 SPS: %v", r.Err())
+	}
+	if sps.Width <= 0 || sps.Height <= 0 {
+		return nil, fmt.Errorf("malformed H.265 SPS: %dx%d", sps.Width, sps.Height)
+	}
+	return sps, nil
+}
+
+func skipH265ScalingListData(r *BitReader) {
+	for sizeID := 0; sizeID < 4; sizeID++ {
+		matrixStep := 1
+		if sizeID == 3 {
+			matrixStep = 3
+		}
+		for matrixID := 0; matrixID < 6; matrixID += matrixStep {
+			if !r.ReadFlag() { // scaling_list_pred_mode_flag
+				r.ReadUE() // scaling_list_pred_matrix_id_delta
+				continue
+			}
+			coefNum := 1 << uint(4+sizeID<<1)
+			if coefNum > 64 {
+				coefNum = 64
+			}
+			if sizeID > 1 {
+				r.ReadSE() // scaling_list_dc_coef_minus8
+			}
+			for i := 0; i < coefNum && r.Err() == nil; i++ {
+				r.ReadSE() // scaling_list_delta_coef
+			}
+		}
+	}
+}
+
+// skipH265ShortTermRefPicSets reads num_short_term_ref_pic_sets and the
+// st_ref_pic_set structures that follow, section 7.3.7.
+func skipH265ShortTermRefPicSets(r *BitReader) {
+	numSets := int(r.ReadUE())
+	if numSets > 64 {
+		r.err = fmt.Errorf("num_short_term_ref_pic_sets %d out of range", numSets)
+		return
+	}
+	numDeltaPocs := make([]int, numSets)
+	for i := 0; i < numSets && r.Err() == nil; i++ {
+		if i != 0 && r.ReadFlag() { // inter_ref_pic_set_prediction_flag
+			r.SkipBits(1) // delta_rps_sign
+			r.ReadUE()    // abs_delta_rps_minus1
+			for j := 0; j <= numDeltaPocs[i-1]; j++ {
+				// a delta is used unless both used_by_curr_pic_flag and
+				// use_delta_flag are zero
+				if r.ReadFlag() || r.ReadFlag() {
+					numDeltaPocs[i]++
+				}
+			}
+			continue
+		}
+		numNegativePics := int(r.ReadUE())
+		numPositivePics := int(r.ReadUE())
+		if numNegativePics+numPositivePics > 32 {
+			r.err = fmt.Errorf("too many short term reference pictures")
+			return
+		}
+		numDeltaPocs[i] = numNegativePics + numPositivePics
+		for j := 0; j < numDeltaPocs[i]; j++ {
+			r.ReadUE()    // delta_poc_s0_minus1 or delta_poc_s1_minus1
+			r.SkipBits(1) // used_by_curr_pic_s0_flag or used_by_curr_pic_s1_flag
+		}
+	}
+}
+
+// FrameRate returns the frame rate given by the VUI timing info, or 0 if
+// there is none.
+func (sps *H265SPS) FrameRate() float64 {
+	if !sps.TimingInfoPresent || sps.NumUnitsInTick == 0 {
+		return 0
+	}
+	return float64(sps.TimeScale) / float64(sps.NumUnitsInTick)
+}
+
+// Level returns the level as written in the specification, e.g. 3.1 for
+// general_level_idc 93.
+func (sps *H265SPS) Level() string {
+	if sps.LevelIDC%30 == 0 {
+		return fmt.Sprintf("%d", sps.LevelIDC/30)
+	}
+	return fmt.Sprintf("%d.%d", sps.LevelIDC/30, sps.LevelIDC%30/3)
+}
@@ -0,0 +1,164 @@
+package commonutilities
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// bitsToBytes packs a string of '0' and '1', ignoring spaces, into bytes
+// padded with zero bits.
+func bitsToBytes(bits string) []byte {
+	bits = strings.Replace(bits, " ", "", -1)
+	data := make([]byte, (len(bits)+7)/8)
+	for i, bit := range bits {
+		if bit == '1' {
+			data[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+	return data
+}
+
+func TestBitReader(t *testing.T) {
+	var tests = []struct {
+		bits string
+		ue   []uint32
+	}{
+		{"1", []uint32{0}},
+		{"010 011", []uint32{1, 2}},
+		{"00100 00101 00110 00111", []uint32{3, 4, 5, 6}},
+		{"0001000", []uint32{7}},
+		{"000000001 00000000", []uint32{255}},
+	}
+	for _, test := range tests {
+		r := NewBitReader(bitsToBytes(test.bits))
+		for _, want := range test.ue {
+			if got := r.ReadUE(); got != want || r.Err() != nil {
+				t.Errorf("ReadUE(%s) = %d, %v, want %d", test.bits, got, r.Err(), want)
+			}
+		}
+	}
+
+	r := NewBitReader(bitsToBytes("1 010 011 00100 00101"))
+	for _, want := range []int32{0, 1, -1, 2, -2} {
+		if got := r.ReadSE(); got != want {
+			t.Errorf("ReadSE() = %d, want %d", got, want)
+		}
+	}
+
+	r = NewBitReader([]byte{0xa5, 0x0f})
+	if got := r.ReadBits(4); got != 0xa {
+		t.Errorf("ReadBits(4) = %x", got)
+	}
+	if got := r.ReadUint8(); got != 0x50 || r.BitsLeft() != 4 {
+		t.Errorf("ReadUint8() = %x, %d bits left", got, r.BitsLeft())
+	}
+	if r.ReadBits(5); r.Err() == nil {
+		t.Errorf("ReadBits past the end did not fail")
+	}
+	if got := r.ReadBits(1); got != 0 {
+		t.Errorf("ReadBits after an error = %d", got)
+	}
+
+	// 32 leading zero bits cannot be a ue(v)
+	if r = NewBitReader(make([]byte, 8)); r.ReadUE() != 0 || r.Err() == nil {
+		t.Errorf("ReadUE(0x0000000000000000) did not fail")
+	}
+}
+
+func TestRemoveEmulationPrevention(t *testing.T) {
+	var tests = []struct {
+		nalUnit string
+		rbsp    string
+	}{
+		{"6742001f", "6742001f"},
+		{"00000301", "000001"},
+		{"0000030000030003", "000000000003"},
+		{"000300", "000300"},
+	}
+	for _, test := range tests {
+		nalUnit, _ := hex.DecodeString(test.nalUnit)
+		if got := hex.EncodeToString(RemoveEmulationPrevention(nalUnit)); got != test.rbsp {
+			t.Errorf("RemoveEmulationPrevention(%s) = %s, want %s", test.nalUnit, got, test.rbsp)
+		}
+	}
+}
+
+// addEmulationPrevention escapes an RBSP the way an encoder does.
+func addEmulationPrevention(rbsp []byte) []byte {
+	var nalUnit []byte
+	zeros := 0
+	for _, b := range rbsp {
+		if zeros >= 2 && b <= 3 {
+			nalUnit = append(nalUnit, 3)
+			zeros = 0
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		nalUnit = append(nalUnit, b)
+	}
+	return nalUnit
+}
+
+func TestParseH264SPS(t *testing.T) {
+	// Baseline 3.1, 1280x720, from the sprop-parameter-sets in TestParseFmtp
+	nalUnit, _ := hex.DecodeString("6742001f9da814016e9b80808081")
+	sps, err := ParseH264SPS(nalUnit)
+	if err != nil {
+		t.Fatalf("ParseH264SPS() = %v", err)
+	}
+	if sps.ProfileIDC != 66 || sps.Level() != "3.1" || sps.Width != 1280 || sps.Height != 720 ||
+		sps.ChromaFormatIDC != 1 || !sps.FrameMBSOnly || sps.TimingInfoPresent || sps.FrameRate() != 0 {
+		t.Errorf("ParseH264SPS() = %+v", sps)
+	}
+
+	// High 4.0, 1920x1088 cropped to 1080, 1:1 samples, 30 frames per second
+	rbsp := bitsToBytes("01100100 00000000 00101000" + // profile_idc, constraint flags, level_idc
+		"1 010 1 1 0 0" + // sps id, chroma_format_idc, bit depths, no scaling matrix
+		"1 1 011 010 0" + // frame_num, poc type 0 and lsb, ref frames, no gaps
+		"0000001111000 0000001000100 1 1" + // 120x68 macroblocks, frame_mbs_only, direct_8x8
+		"1 1 1 1 00101" + // crop 4 chroma lines at the bottom
+		"1 1 00000001 0 0 0" + // VUI with aspect_ratio_idc 1
+		"1 00000000000000000000000000000001 00000000000000000000000000111100 1" + // 1/60 ticks, fixed
+		"1")
+	if sps, err = ParseH264SPS(append([]byte{0x67}, addEmulationPrevention(rbsp)...)); err != nil {
+		t.Fatalf("ParseH264SPS() = %v", err)
+	}
+	if sps.ProfileIDC != 100 || sps.Level() != "4" || sps.Width != 1920 || sps.Height != 1080 || sps.CropBottom != 8 ||
+		sps.SARWidth != 1 || sps.SARHeight != 1 || sps.NumUnitsInTick != 1 || sps.TimeScale != 60 || !sps.FixedFrameRate || sps.FrameRate() != 30 {
+		t.Errorf("ParseH264SPS() = %+v", sps)
+	}
+
+	if _, err = ParseH264SPS(nalUnit[:6]); err == nil {
+		t.Errorf("ParseH264SPS(%x) did not fail", nalUnit[:6])
+	}
+	pps, _ := hex.DecodeString("68ce3c80")
+	if _, err = ParseH264SPS(pps); err == nil {
+		t.Errorf("ParseH264SPS(%x) did not fail", pps)
+	}
+}
+
+func TestParseH265SPS(t *testing.T) {
+	// Main 3.1, 1280x720, from the sprop-sps in TestParseFmtp
+	nalUnit, _ := base64.StdEncoding.DecodeString("QgEBAWAAAAMAkAAAAwAAAwBdoAKAgC0WWVmkkyvAQAAA+kAAF3AC")
+	sps, err := ParseH265SPS(nalUnit)
+	if err != nil {
+		t.Fatalf("ParseH265SPS() = %v", err)
+	}
+	if sps.ProfileIDC != 1 || sps.TierFlag || sps.LevelIDC != 93 || sps.Level() != "3.1" || sps.Width != 1280 || sps.Height != 720 ||
+		sps.ChromaFormatIDC != 1 || sps.BitDepthLuma != 8 || sps.SARWidth != 1 || sps.SARHeight != 1 {
+		t.Errorf("ParseH265SPS() = %+v", sps)
+	}
+
+	if _, err = ParseH265SPS(nalUnit[:20]); err == nil {
+		t.Errorf("ParseH265SPS(%x) did not fail", nalUnit[:20])
+	}
+	if _, err = ParseH265SPS(bytes.Repeat([]byte{0x40, 0x01}, 8)); err == nil {
+		t.Errorf("ParseH265SPS(VPS) did not fail")
+	}
+}
@@ -1,29 +1,105 @@
 package commonutilities
 
-import "fmt"
-
-type StringParser struct {
-	buffer        string
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Parser walks over a string or a byte slice. Every token it returns is a
+// sub-slice of the parsed buffer, so a BytesParser can work directly on the
+// packet read from a net.Conn without copying it.
+type Parser[T string | []byte] struct {
+	buffer        T
 	curLineNumber int
+	// curColumn counts bytes and curRuneColumn counts UTF-8 characters from
+	// the start of the current line, both from 1
+	curColumn     int
+	curRuneColumn int
 	startIndex    int
 	endIndex      int
+
+	// incremental parsers treat the end of the buffer as "not yet received"
+	// rather than as the end of the message, see SetIncremental.
+	incremental  bool
+	needMoreData bool
+
+	// maxDigits limits the numbers ConsumeUint64, ConsumeInt64 and
+	// ConsumeHex accept, 0 for no limit
+	maxDigits int
 }
 
+// StringParser parses a string.
+type StringParser = Parser[string]
+
+// BytesParser parses a byte slice. The returned tokens share memory with the
+// slice handed to NewBytes.
+type BytesParser = Parser[[]byte]
+
 func New(inString string) *StringParser {
-	inLen := len(inString)
+	return newParser(inString)
+}
+
+func NewBytes(inBytes []byte) *BytesParser {
+	return newParser(inBytes)
+}
+
+func newParser[T string | []byte](inBuffer T) *Parser[T] {
+	inLen := len(inBuffer)
 	var startIndex, endIndex = -1, -1
 	if inLen > 0 {
 		startIndex = 0
 		endIndex = inLen
 	}
-	return &StringParser{buffer: inString, curLineNumber: 1, startIndex: startIndex, endIndex: endIndex}
+	return &Parser[T]{buffer: inBuffer, curLineNumber: 1, curColumn: 1, curRuneColumn: 1, startIndex: startIndex, endIndex: endIndex}
 }
 
 //GetBuffer:
-//Returns a pointer to the string object
-func (s *StringParser) GetStream() string { return s.buffer }
+//Returns the buffer being parsed
+func (s *Parser[T]) GetStream() T { return s.buffer }
+
+// SetIncremental
+// In incremental mode a Consume*, Expect* or GetThru* call that runs into the
+// end of the buffer before finding its stop condition does not return a short
+// token. Instead it leaves the parser where the call started, returns an empty
+// token (or false) and NeedMoreData reports true until Feed is called. Once the
+// caller has fed more data it repeats the call and parsing resumes exactly
+// where it stopped. Turn incremental mode off when the last piece of the
+// message has been fed so the end of the buffer terminates tokens again.
+func (s *Parser[T]) SetIncremental(incremental bool) {
+	s.incremental = incremental
+	if !incremental {
+		s.needMoreData = false
+	}
+}
+
+// NeedMoreData
+// Returns true if an incremental parser stopped at the end of the buffer.
+// Every Consume* call is a no-op until Feed is called.
+func (s *Parser[T]) NeedMoreData() bool { return s.needMoreData }
+
+// Feed
+// Appends inData to the buffer being parsed and clears NeedMoreData.
+// Tokens returned before the call remain valid.
+func (s *Parser[T]) Feed(inData T) {
+	switch buffer := any(&s.buffer).(type) {
+	case *string:
+		*buffer += string(inData)
+	case *[]byte:
+		*buffer = append(*buffer, []byte(inData)...)
+	}
+	if len(s.buffer) > 0 {
+		if s.startIndex == -1 {
+			s.startIndex = 0
+		}
+		s.endIndex = len(s.buffer)
+	}
+	s.needMoreData = false
+}
 
-func (s *StringParser) ParserIsEmpty() bool {
+func (s *Parser[T]) ParserIsEmpty() bool {
 	if len(s.buffer) == 0 {
 		return true
 	}
@@ -39,29 +115,36 @@ func (s *StringParser) ParserIsEmpty() bool {
 	return false // parser ok to parse
 }
 
-func (s *StringParser) ConsumeWord() string {
+func (s *Parser[T]) ConsumeWord() T {
 	return s.ConsumeUntil(sNonWordMask)
 }
 
+// ConsumeWordUnicode
+// Like ConsumeWord, but words are made of letters of any script, so stream
+// names such as 摄像头_前门 are not split at the first non-ASCII byte.
+func (s *Parser[T]) ConsumeWordUnicode() T {
+	return s.ConsumeUntilRune(sNonWordRuneMask)
+}
+
 // ConsumeWhitespace
 // Keeps on going until non-whitespace
-func (s *StringParser) ConsumeWhitespace(){
+func (s *Parser[T]) ConsumeWhitespace(){
 	s.ConsumeUntil(sWhitespaceMask)
 }
 
 // ConsumeUntilWhitespace
 //+ rt 8.19.99
 //returns whatever is avaliable until non-whitespace
-func (s *StringParser) ConsumeUntilWhitespace() string {
+func (s *Parser[T]) ConsumeUntilWhitespace() T {
 	return s.ConsumeUntil(sEOLWhitespaceMask)
 }
 
-func (s *StringParser) ConsumeUntilDigit() string {
+func (s *Parser[T]) ConsumeUntilDigit() T {
 	return s.ConsumeUntil(sDigitMask)
 }
 
 //Returns the current character, doesn't move past it.
-func (s *StringParser) PeekFast() byte {
+func (s *Parser[T]) PeekFast() byte {
 	if len(s.buffer) != s.startIndex && s.startIndex != -1 {
 		return s.buffer[s.startIndex]
 	} else {
@@ -69,110 +152,357 @@ func (s *StringParser) PeekFast() byte {
 	}
 }
 
-func (s *StringParser) GetCurrentPosition() int {
+func (s *Parser[T]) GetCurrentPosition() int {
 	return s.startIndex
 }
 
-func (s *StringParser) GetCurrentLineNumber() int {
+func (s *Parser[T]) GetCurrentLineNumber() int {
 	return s.curLineNumber
 }
 
+// GetCurrentColumn
+// Returns the byte column on the current line, counting from 1.
+func (s *Parser[T]) GetCurrentColumn() int {
+	return s.curColumn
+}
+
+// GetCurrentRuneColumn
+// Returns the column on the current line in UTF-8 characters, counting from 1.
+// A character is counted once its first byte has been consumed.
+func (s *Parser[T]) GetCurrentRuneColumn() int {
+	return s.curRuneColumn
+}
+
 // ConsumeUntilStop
 //Returns all the data before inStopChar
-func (s *StringParser) ConsumeUntilStop(inStop byte) string {
-	if s.ParserIsEmpty() {
-		return ""
+func (s *Parser[T]) ConsumeUntilStop(inStop byte) T {
+	if s.exhausted() {
+		return s.empty()
 	}
 
-	originalStartIndex := s.startIndex
+	mark := s.Mark()
 	for (s.startIndex < s.endIndex) && (s.buffer[s.startIndex] != inStop) {
 		s.advanceMark()
 	}
-	return s.buffer[originalStartIndex:s.startIndex]
+	if s.ranDry(mark) {
+		return s.empty()
+	}
+	return s.buffer[mark.startIndex:s.startIndex]
 }
 
 // ConsumeUntil
-//Assumes 'inMask' is a 255-char array of booleans. Set this array
-//to a mask of what the stop characters are. true means stop character.
-//You may also pass in one of the many prepackaged masks defined above.
-func (s *StringParser) ConsumeUntil(inMask []uint8) string {
+//Returns all the data before the first byte contained in 'inMask'.
+//Build the mask of stop characters with MaskOf, MaskRange or MaskFunc,
+//or pass in one of the many prepackaged masks in mask.go.
+func (s *Parser[T]) ConsumeUntil(inMask Mask) T {
+	if s.exhausted() {
+		return s.empty()
+	}
+
+	mark := s.Mark()
+	for (s.startIndex < s.endIndex) && !inMask.Contains(s.buffer[s.startIndex]) {
+		s.advanceMark()
+	}
+	if s.ranDry(mark) {
+		return s.empty()
+	}
+	return s.buffer[mark.startIndex:s.startIndex]
+}
+
+// ConsumeUntilRune
+// Returns all the data before the first UTF-8 character contained in
+// 'inMask'. Invalid bytes are passed to the mask as utf8.RuneError.
+func (s *Parser[T]) ConsumeUntilRune(inMask RuneMask) T {
+	if s.exhausted() {
+		return s.empty()
+	}
+
+	mark := s.Mark()
+	for s.startIndex < s.endIndex {
+		r, size, full := s.decodeRune()
+		if !full && s.incremental {
+			// the rest of the character has not arrived yet
+			s.Reset(mark)
+			s.needMoreData = true
+			return s.empty()
+		}
+		if inMask(r) {
+			break
+		}
+		for ; size > 0; size-- {
+			s.advanceMark()
+		}
+	}
+	if s.ranDry(mark) {
+		return s.empty()
+	}
+	return s.buffer[mark.startIndex:s.startIndex]
+}
+
+// PeekRune
+// Returns the current UTF-8 character and its length in bytes without moving
+// past it. Returns utf8.RuneError and 0 if the parser is empty, and
+// utf8.RuneError and 1 for an invalid byte.
+func (s *Parser[T]) PeekRune() (r rune, size int) {
 	if s.ParserIsEmpty() {
-		return ""
+		return utf8.RuneError, 0
 	}
+	r, size, _ = s.decodeRune()
+	return
+}
 
-	originalStartIndex := s.startIndex
-	for (s.startIndex < s.endIndex) && !(inMask[uint8(s.buffer[s.startIndex])] == 1) {
+// ConsumeRune
+// Returns the current UTF-8 character, like PeekRune, and moves past it.
+// An incremental parser holding the first bytes of a character asks for
+// more data instead.
+func (s *Parser[T]) ConsumeRune() (r rune, size int) {
+	if s.exhausted() {
+		return utf8.RuneError, 0
+	}
+	r, size, full := s.decodeRune()
+	if !full && s.incremental {
+		s.needMoreData = true
+		return utf8.RuneError, 0
+	}
+	for i := 0; i < size; i++ {
 		s.advanceMark()
 	}
-	return s.buffer[originalStartIndex:s.startIndex]
+	return r, size
 }
 
-func (s *StringParser) ConsumeLength(inLength int) string {
-	if s.ParserIsEmpty(){
-		return ""
+// ConsumeLength
+// Returns the next inLength bytes, or fewer at the end of the buffer. A
+// negative length backs up instead and returns an empty token.
+func (s *Parser[T]) ConsumeLength(inLength int) T {
+	if inLength < 0 {
+		s.backUp(-inLength)
+		return s.empty()
+	}
+	if s.exhausted() {
+		return s.empty()
 	}
 	//sanity check to make sure we aren't being told to run off the end of the
 	//buffer
 	if(s.endIndex - s.startIndex) < inLength {
+		if s.incremental {
+			s.needMoreData = true
+			return s.empty()
+		}
 		inLength = s.endIndex - s.startIndex
 	}
 	ret := s.buffer[s.startIndex:s.startIndex+inLength]
-
-	if inLength>0{
-		for i:=0; i< inLength; i++{
-			s.advanceMark()
-		}
-	} else {
-		s.startIndex += inLength // ***may mess up line number if we back up too much
+	for i:=0; i< inLength; i++{
+		s.advanceMark()
 	}
-
 	return  ret
 }
 
+// ParserMark is a saved parser position, see Mark.
+type ParserMark struct {
+	startIndex int
+	lineNumber int
+	column     int
+	runeColumn int
+}
+
+// Mark
+// Returns the current position so a speculative parse can be rolled back
+// with Reset, e.g. trying one grammar alternative and then another.
+func (s *Parser[T]) Mark() ParserMark {
+	return ParserMark{startIndex: s.startIndex, lineNumber: s.curLineNumber, column: s.curColumn, runeColumn: s.curRuneColumn}
+}
+
+// Reset
+// Moves the parser back (or forward) to a position returned by Mark,
+// restoring the line number and column. Marks stay valid across Feed.
+func (s *Parser[T]) Reset(mark ParserMark) {
+	s.startIndex, s.curLineNumber = mark.startIndex, mark.lineNumber
+	s.curColumn, s.curRuneColumn = mark.column, mark.runeColumn
+}
+
 // ConsumeInteger
-// Returns whatever integer is currently in the stream
-func (s *StringParser) ConsumeInteger() ( outString string, theValue uint32) {
-	if s.ParserIsEmpty(){
+// Returns whatever integer is currently in the stream. The value wraps
+// around past 4294967295, use ConsumeUint64 or ConsumeIntegerErr to detect
+// overflow.
+func (s *Parser[T]) ConsumeInteger() ( outString T, theValue uint32) {
+	if s.exhausted() {
 		return
 	}
 
-	originalStartIndex := s.startIndex
+	mark := s.Mark()
 	for (s.startIndex < s.endIndex) && (s.buffer[s.startIndex] >= '0') && (s.buffer[s.startIndex] <= '9') {
 		theValue = (theValue * 10 ) + uint32(s.buffer[s.startIndex] - '0')
 		s.advanceMark()
 	}
-	outString = s.buffer[originalStartIndex:s.startIndex]
+	if s.ranDry(mark) {
+		return s.empty(), 0
+	}
+	outString = s.buffer[mark.startIndex:s.startIndex]
 	return
 }
 
-func (s *StringParser) ConsumeFloat() ( theFloat float32) {
-	if s.ParserIsEmpty(){
-		return
+// SetMaxDigits
+// Makes ConsumeUint64, ConsumeInt64 and ConsumeHex reject numbers of more
+// than maxDigits digits, leading zeros included, so that a hostile
+// "CSeq: 99999999999999999999" fails early. 0 removes the limit.
+func (s *Parser[T]) SetMaxDigits(maxDigits int) {
+	s.maxDigits = maxDigits
+}
+
+// ConsumeUint64
+// Returns the decimal integer at the current position. On failure nothing
+// is consumed and the error is a *ParseError, wrapping strconv.ErrRange if
+// the number overflows or is longer than SetMaxDigits allows.
+func (s *Parser[T]) ConsumeUint64() (uint64, error) {
+	return s.consumeDigits(10, "integer")
+}
+
+// ConsumeInt64
+// Like ConsumeUint64, but accepts a leading '+' or '-'.
+func (s *Parser[T]) ConsumeInt64() (int64, error) {
+	mark := s.Mark()
+	negative := s.Expect('-')
+	if !negative {
+		s.Expect('+')
+	}
+	magnitude, err := s.consumeDigits(10, "integer")
+	if err != nil {
+		s.Reset(mark)
+		return 0, err
+	}
+	switch {
+	case magnitude <= math.MaxInt64 && negative:
+		return -int64(magnitude), nil
+	case magnitude <= math.MaxInt64:
+		return int64(magnitude), nil
+	case magnitude == math.MaxInt64+1 && negative:
+		return math.MinInt64, nil
+	}
+	return 0, s.rangeError(mark, "integer", fmt.Errorf("integer overflows 64 bits: %w", strconv.ErrRange))
+}
+
+// ConsumeHex
+// Like ConsumeUint64, but reads hexadecimal digits without a 0x prefix,
+// e.g. the ssrc=ABCD1234 of a Transport header.
+func (s *Parser[T]) ConsumeHex() (uint64, error) {
+	return s.consumeDigits(16, "hexadecimal integer")
+}
+
+func (s *Parser[T]) consumeDigits(base uint64, expected string) (theValue uint64, err error) {
+	mark := s.Mark()
+	if s.exhausted() {
+		return 0, s.failAt(mark, expected)
 	}
-	for (s.startIndex < s.endIndex) && (s.buffer[s.startIndex] >= '0') && (s.buffer[s.startIndex] <= '9') {
-		theFloat = (theFloat * 10) + float32(s.buffer[s.startIndex] - '0')
+	overflow := false
+	for s.startIndex < s.endIndex {
+		digit, ok := digitValue(s.buffer[s.startIndex], base)
+		if !ok {
+			break
+		}
+		if theValue > (math.MaxUint64-digit)/base {
+			overflow = true
+		}
+		theValue = theValue*base + digit
 		s.advanceMark()
 	}
-	if (s.startIndex < s.endIndex) && s.GetCurrentPosition() == '.' {
-		s.advanceMark()
+	if s.ranDry(mark) {
+		return 0, ErrNeedMoreData
 	}
+	switch digits := s.startIndex - mark.startIndex; {
+	case digits == 0:
+		return 0, s.failAt(mark, expected)
+	case s.maxDigits > 0 && digits > s.maxDigits:
+		return 0, s.rangeError(mark, expected, fmt.Errorf("%s longer than %d digits: %w", expected, s.maxDigits, strconv.ErrRange))
+	case overflow:
+		return 0, s.rangeError(mark, expected, fmt.Errorf("%s overflows 64 bits: %w", expected, strconv.ErrRange))
+	}
+	return theValue, nil
+}
+
+func digitValue(c byte, base uint64) (uint64, bool) {
+	var digit uint64
+	switch {
+	case c >= '0' && c <= '9':
+		digit = uint64(c - '0')
+	case c >= 'a' && c <= 'f':
+		digit = uint64(c-'a') + 10
+	case c >= 'A' && c <= 'F':
+		digit = uint64(c-'A') + 10
+	default:
+		return 0, false
+	}
+	return digit, digit < base
+}
+
+// rangeError moves back to mark and returns a ParseError wrapping err.
+func (s *Parser[T]) rangeError(mark ParserMark, expected string, err error) error {
+	s.Reset(mark)
+	parseError := s.NewParseError(expected)
+	parseError.Err = err
+	return parseError
+}
+
+// ConsumeFloat
+// Returns the decimal floating point number at the current position, with
+// an optional sign, fraction and exponent, e.g. -1.5, .25 or 2.5e-3. The
+// result is correctly rounded, as strconv.ParseFloat would round it. A
+// number too large for a float64 is returned as ±Inf. Returns 0 and
+// consumes nothing if there is no number.
+func (s *Parser[T]) ConsumeFloat() (theFloat float64) {
+	theFloat, _ = s.consumeFloat()
+	return
+}
+
+// consumeFloat scans the syntax of a number and leaves the rounding to
+// strconv.ParseFloat. A number out of range is consumed and reported with
+// an error wrapping strconv.ErrRange.
+func (s *Parser[T]) consumeFloat() (float64, error) {
+	mark := s.Mark()
+	if s.exhausted() {
+		return 0, s.failAt(mark, "number")
+	}
+	if !s.Expect('-') {
+		s.Expect('+')
+	}
+	digits := s.skipDigits()
+	if s.Expect('.') {
+		digits += s.skipDigits()
+	}
+	if digits > 0 {
+		exponentMark := s.Mark()
+		if s.Expect('e') || s.Expect('E') {
+			if !s.Expect('-') {
+				s.Expect('+')
+			}
+			// a lone 'e' is not part of the number
+			if s.skipDigits() == 0 && !s.needMoreData {
+				s.Reset(exponentMark)
+			}
+		}
+	}
+	if digits == 0 || s.needMoreData {
+		return 0, s.failAt(mark, "number")
+	}
+	return strconv.ParseFloat(string(s.buffer[mark.startIndex:s.startIndex]), 64)
+}
 
-	multiplier := float32(.1)
-	for (s.startIndex < s.endIndex) && (s.buffer[s.startIndex] >= '0') && (s.buffer[s.startIndex] <= '9')   {
-		theFloat += float32(multiplier * float32(s.buffer[s.startIndex] - '0'))
-		multiplier *= float32(.1)
+// skipDigits moves past decimal digits and returns how many there were.
+func (s *Parser[T]) skipDigits() (digits int) {
+	for (s.startIndex < s.endIndex) && (s.buffer[s.startIndex] >= '0') && (s.buffer[s.startIndex] <= '9') {
 		s.advanceMark()
+		digits++
 	}
 	return
 }
 
-func (s *StringParser) ConsumeNPT() (theFloat float32) {
-	if s.ParserIsEmpty(){
+func (s *Parser[T]) ConsumeNPT() (theFloat float32) {
+	if s.exhausted() {
 		return
 	}
 	valArray := [4]float32{0,0,0,0}
 	divArray := [4]float32{1,1,1,1}
 	valType,index := 0,0
+	mark := s.Mark()
 	for index = 0; index < 4 ; index++ {
 		for (s.startIndex < s.endIndex) && (s.buffer[s.startIndex] >= '0') && (s.buffer[s.startIndex] <= '9') {
 			valArray[index] = (valArray[index] * 10) + float32(s.buffer[s.startIndex] - '0')
@@ -193,6 +523,9 @@ func (s *StringParser) ConsumeNPT() (theFloat float32) {
 		}
 		s.advanceMark()
 	}
+	if s.ranDry(mark) {
+		return 0
+	}
 	if valType == 0 {
 		theFloat = valArray[0] + (valArray[1] / divArray[1])
 	} else {
@@ -201,8 +534,59 @@ func (s *StringParser) ConsumeNPT() (theFloat float32) {
 	return
 }
 
-func (s *StringParser) Expect(stopChar byte) bool {
-	if s.ParserIsEmpty() {
+// ConsumeNPTDuration
+// Like ConsumeNPT, but returns a time.Duration which keeps nanosecond
+// precision however long the media is. Accepts seconds ("123.45") and
+// hours:minutes:seconds ("1:02:03.45"). Returns false and consumes
+// nothing if there is no npt time.
+func (s *Parser[T]) ConsumeNPTDuration() (theDuration time.Duration, ok bool) {
+	if s.exhausted() {
+		return
+	}
+	mark := s.Mark()
+	var valArray [3]int64
+	index := 0
+	for ; index < 3; index++ {
+		digitStartIndex := s.startIndex
+		for (s.startIndex < s.endIndex) && (s.buffer[s.startIndex] >= '0') && (s.buffer[s.startIndex] <= '9') {
+			valArray[index] = (valArray[index] * 10) + int64(s.buffer[s.startIndex] - '0')
+			s.advanceMark()
+		}
+		if s.startIndex == digitStartIndex {
+			s.Reset(mark)
+			return 0, false
+		}
+		if index == 2 || s.startIndex == s.endIndex || s.buffer[s.startIndex] != ':' {
+			break
+		}
+		s.advanceMark()
+	}
+	if index == 1 {
+		// npt-hhmmss needs all three fields
+		s.Reset(mark)
+		return 0, false
+	}
+	seconds := valArray[0]
+	if index == 2 {
+		seconds = (valArray[0] * 3600) + (valArray[1] * 60) + valArray[2]
+	}
+
+	var nanoseconds int64
+	if (s.startIndex < s.endIndex) && s.buffer[s.startIndex] == '.' {
+		s.advanceMark()
+		for scale := int64(time.Second / 10); (s.startIndex < s.endIndex) && (s.buffer[s.startIndex] >= '0') && (s.buffer[s.startIndex] <= '9'); scale /= 10 {
+			nanoseconds += scale * int64(s.buffer[s.startIndex] - '0')
+			s.advanceMark()
+		}
+	}
+	if s.ranDry(mark) {
+		return 0, false
+	}
+	return time.Duration(seconds)*time.Second + time.Duration(nanoseconds), true
+}
+
+func (s *Parser[T]) Expect(stopChar byte) bool {
+	if s.exhausted() {
 		return false
 	}
 	if s.startIndex > s.endIndex {
@@ -217,8 +601,90 @@ func (s *StringParser) Expect(stopChar byte) bool {
 	}
 }
 
-func (s *StringParser) ExpectEOL() bool {
-	if s.ParserIsEmpty() {
+// ExpectString
+// Moves past inString if the data starts with it and returns true. Nothing is
+// consumed otherwise. An incremental parser holding only the beginning of
+// inString asks for more data.
+func (s *Parser[T]) ExpectString(inString string) bool {
+	return s.expectPrefix(inString, false)
+}
+
+// ExpectFold
+// Same as ExpectString, but ASCII letters match regardless of case, e.g.
+// ExpectFold("rtsp://") accepts "RTSP://".
+func (s *Parser[T]) ExpectFold(inString string) bool {
+	return s.expectPrefix(inString, true)
+}
+
+func (s *Parser[T]) expectPrefix(inString string, fold bool) bool {
+	if s.exhausted() {
+		return false
+	}
+	remaining := s.PeekN(len(inString))
+	if !hasPrefix(remaining, inString[:len(remaining)], fold) {
+		return false
+	}
+	if len(remaining) < len(inString) {
+		s.needMoreData = s.incremental
+		return false
+	}
+	for i := 0; i < len(inString); i++ {
+		s.advanceMark()
+	}
+	return true
+}
+
+// HasPrefix
+// Returns true if the data starts with inString. Nothing is consumed.
+func (s *Parser[T]) HasPrefix(inString string) bool {
+	return hasPrefix(s.PeekN(len(inString)), inString, false)
+}
+
+// HasPrefixFold
+// Same as HasPrefix, but ASCII letters match regardless of case.
+func (s *Parser[T]) HasPrefixFold(inString string) bool {
+	return hasPrefix(s.PeekN(len(inString)), inString, true)
+}
+
+// PeekN
+// Returns the next inLength bytes, or fewer at the end of the buffer,
+// without moving past them.
+func (s *Parser[T]) PeekN(inLength int) T {
+	if s.ParserIsEmpty() || inLength <= 0 {
+		return s.empty()
+	}
+	if s.endIndex - s.startIndex < inLength {
+		inLength = s.endIndex - s.startIndex
+	}
+	return s.buffer[s.startIndex:s.startIndex + inLength]
+}
+
+// hasPrefix compares data with the whole of prefix, ignoring the case of
+// ASCII letters if fold is set.
+func hasPrefix[T string | []byte](data T, prefix string, fold bool) bool {
+	if len(data) != len(prefix) {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		if data[i] == prefix[i] {
+			continue
+		}
+		if !fold || toLowerASCII(data[i]) != toLowerASCII(prefix[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func toLowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func (s *Parser[T]) ExpectEOL() bool {
+	if s.exhausted() {
 		return false
 	}
 
@@ -227,7 +693,12 @@ func (s *StringParser) ExpectEOL() bool {
 	retVal := false
 	if (s.startIndex < s.endIndex) && ((s.buffer[s.startIndex] == '\r') || (s.buffer[s.startIndex] == '\n')) {
 		retVal = true
+		mark := s.Mark()
 		s.advanceMark()
+		//a trailing \r may be the first half of a \r\n
+		if s.buffer[mark.startIndex] == '\r' && s.ranDry(mark) {
+			return false
+		}
 		//check for a \r\n, which is the most common EOL sequence.
 		if (s.startIndex < s.endIndex) && (s.buffer[s.startIndex - 1] == '\r') && (s.buffer[s.startIndex] == '\n') {
 			s.advanceMark()
@@ -236,62 +707,121 @@ func (s *StringParser) ExpectEOL() bool {
 	return retVal
 }
 
-func (s *StringParser) ConsumeEOL() (outString string) {
-	if s.ParserIsEmpty() {
+func (s *Parser[T]) ConsumeEOL() (outString T) {
+	if s.exhausted() {
 		return
 	}
 
 	//This function processes all legal forms of HTTP / RTSP eols.
 	//They are: \r (alone), \n (alone), \r\n
-	originalStartIndex := s.startIndex
+	mark := s.Mark()
 	if (s.startIndex < s.endIndex) && ((s.buffer[s.startIndex] == '\r') || (s.buffer[s.startIndex] == '\n')) {
 		s.advanceMark()
+		//a trailing \r may be the first half of a \r\n
+		if s.buffer[mark.startIndex] == '\r' && s.ranDry(mark) {
+			return
+		}
 		//check for a \r\n, which is the most common EOL sequence.
 		if (s.startIndex < s.endIndex) && (s.buffer[s.startIndex - 1] == '\r') && (s.buffer[s.startIndex] == '\n') {
 			s.advanceMark()
 		}
 	}
-	outString = s.buffer[originalStartIndex:s.startIndex]
+	outString = s.buffer[mark.startIndex:s.startIndex]
 	return
 }
 
 //GetThru:
 //Works very similar to ConsumeUntil except that it moves past the stop token,
 //and if it can't find the stop token it returns false
-func (s *StringParser) GetThru(stopChar byte) (outString string, outBool bool) {
+func (s *Parser[T]) GetThru(stopChar byte) (outString T, outBool bool) {
+	mark := s.Mark()
 	outString = s.ConsumeUntilStop(stopChar)
 	outBool = s.Expect(stopChar)
+	if s.needMoreData {
+		s.Reset(mark)
+		return s.empty(), false
+	}
 	return
 }
 
 //GetThruEOL:
-func (s *StringParser) GetThruEOL() (outString string, outBool bool) {
+func (s *Parser[T]) GetThruEOL() (outString T, outBool bool) {
+	mark := s.Mark()
 	outString = s.ConsumeUntil(sEOLMask)
 	outBool = s.ExpectEOL()
+	if s.needMoreData {
+		s.Reset(mark)
+		return s.empty(), false
+	}
 	return
 }
 
+// stop at the end or at an escape of a quoted-string
+var sQuotedStringStopConditions = MaskOf("\"\\")
+
+// ConsumeQuotedString
+// Reads a quoted-string, RFC 7230 section 3.2.6, and returns its content
+// without the quotes and with backslash escapes undone, e.g. "a \"b\""
+// becomes a "b". The token shares memory with the buffer unless it held an
+// escape. On failure nothing is consumed and the error is a *ParseError,
+// or ErrNeedMoreData if an incremental parser has not seen the closing quote.
+func (s *Parser[T]) ConsumeQuotedString() (T, error) {
+	mark := s.Mark()
+	if !s.Expect('"') {
+		return s.empty(), s.failAt(mark, "quoted-string")
+	}
+	var unescaped []byte
+	escaped := false
+	for !s.ParserIsEmpty() {
+		contentStart := s.startIndex
+		s.ConsumeUntil(sQuotedStringStopConditions)
+		if s.needMoreData {
+			break
+		}
+		unescaped = append(unescaped, s.buffer[contentStart:s.startIndex]...)
+		if s.Expect('"') {
+			if !escaped {
+				return s.buffer[mark.startIndex+1 : s.startIndex-1], nil
+			}
+			return T(unescaped), nil
+		}
+		s.Expect('\\')
+		escaped = true
+		if quoted := s.ConsumeLength(1); len(quoted) == 1 {
+			unescaped = append(unescaped, quoted[0])
+		}
+	}
+	if s.ParserIsEmpty() && s.incremental {
+		s.needMoreData = true
+	}
+	return s.empty(), s.failAt(mark, "closing '\"'")
+}
+
 // UnQuote　去掉字符串中的引号
-// If a string is contained within double or single quotes
-// then UnQuote() will remove them. - [sfu]
-func (s *StringParser) UnQuote(inString string) string {
+// If a string is contained within a matching pair of double or single quotes
+// then UnQuote() will remove them. Backslash escapes inside double quotes are
+// undone as ConsumeQuotedString does. Anything else, such as 'abc" or an
+// unterminated "abc\", is returned unchanged.
+func (s *Parser[T]) UnQuote(inString T) T {
 	// sanity check
 	if len(inString) < 2 { return inString }
 
-	// remove begining quote if it's there.
-	if inString[0] == '"' || inString[0] == '\'' {
-		inString = inString[1:]
-	}
-
-	// remove ending quote if it's there.
-	if inString[len(inString) - 1] == '"' || inString[len(inString) - 1] == '\'' {
-		inString = inString[0:len(inString) - 1]
+	switch inString[0] {
+	case '"':
+		quoted := newParser(inString)
+		if unquoted, err := quoted.ConsumeQuotedString(); err == nil && quoted.ParserIsEmpty() {
+			return unquoted
+		}
+	case '\'':
+		if inString[len(inString) - 1] == '\'' {
+			return inString[1:len(inString) - 1]
+		}
 	}
 	return inString
 }
 
 //Returns some info about the stream
-func (s *StringParser) GetDataParsedLen() (theValue int) {
+func (s *Parser[T]) GetDataParsedLen() (theValue int) {
 	theValue = s.startIndex
 	if theValue < 0 {
 		panic(fmt.Sprintf("s.startIndex = %d < 0",theValue))
@@ -299,7 +829,7 @@ func (s *StringParser) GetDataParsedLen() (theValue int) {
 	return
 }
 
-func (s *StringParser) GetDataReceivedLen() (theValue int) {
+func (s *Parser[T]) GetDataReceivedLen() (theValue int) {
 	theValue = len(s.buffer)
 	if theValue < 0 {
 		panic(fmt.Sprintf("len(s.buffer) = %d < 0",theValue))
@@ -307,7 +837,7 @@ func (s *StringParser) GetDataReceivedLen() (theValue int) {
 	return
 }
 
-func (s *StringParser) GetDataRemaining() (theValue int) {
+func (s *Parser[T]) GetDataRemaining() (theValue int) {
 	theValue = s.endIndex - s.startIndex
 	if theValue < 0 {
 		panic(fmt.Sprintf("s.endIndex - s.startIndex = %d < 0",theValue))
@@ -315,14 +845,104 @@ func (s *StringParser) GetDataRemaining() (theValue int) {
 	return
 }
 
-func (s *StringParser) advanceMark() {
+// exhausted reports whether there is nothing left to parse. An incremental
+// parser that runs dry asks for more data.
+func (s *Parser[T]) exhausted() bool {
+	if s.needMoreData {
+		return true
+	}
+	if s.ParserIsEmpty() {
+		s.needMoreData = s.incremental
+		return true
+	}
+	return false
+}
+
+// ranDry is called after a scan started at mark. If an incremental parser hit
+// the end of the buffer before the stop condition, the scan is undone and
+// more data is requested.
+func (s *Parser[T]) ranDry(mark ParserMark) bool {
+	if !s.incremental || s.startIndex < s.endIndex {
+		return false
+	}
+	s.Reset(mark)
+	s.needMoreData = true
+	return true
+}
+
+// decodeRune decodes the UTF-8 character at the current position. full is
+// false if the buffer ends in the middle of the character.
+func (s *Parser[T]) decodeRune() (r rune, size int, full bool) {
+	switch buffer := any(&s.buffer).(type) {
+	case *string:
+		remaining := (*buffer)[s.startIndex:s.endIndex]
+		r, size = utf8.DecodeRuneInString(remaining)
+		full = utf8.FullRuneInString(remaining)
+	case *[]byte:
+		remaining := (*buffer)[s.startIndex:s.endIndex]
+		r, size = utf8.DecodeRune(remaining)
+		full = utf8.FullRune(remaining)
+	}
+	return
+}
+
+// empty returns a zero length token
+func (s *Parser[T]) empty() T {
+	var empty T
+	return empty
+}
+
+// backUp moves the parser back by up to inLength bytes, undoing the line
+// and column counting of advanceMark.
+func (s *Parser[T]) backUp(inLength int) {
+	for ; inLength > 0 && s.startIndex > 0; inLength-- {
+		s.startIndex--
+		if s.endsLine(s.startIndex) {
+			s.curLineNumber--
+		}
+	}
+	lineStart := s.lineStart(s.startIndex)
+	s.curColumn, s.curRuneColumn = s.startIndex-lineStart+1, 1
+	for i := lineStart; i < s.startIndex; i++ {
+		if s.buffer[i]&0xc0 != 0x80 {
+			s.curRuneColumn++
+		}
+	}
+}
+
+// endsLine reports whether moving past the byte at index starts a new line.
+// A \r followed by \n does not, the \n does.
+func (s *Parser[T]) endsLine(index int) bool {
+	return (s.buffer[index] == '\n') || ((s.buffer[index] == '\r') && (index+1 == s.endIndex || s.buffer[index+1] != '\n'))
+}
+
+// lineStart returns the index of the first byte of the line holding index.
+func (s *Parser[T]) lineStart(index int) int {
+	for index > 0 && !s.endsLine(index-1) {
+		index--
+	}
+	return index
+}
+
+func (s *Parser[T]) advanceMark() {
 	if s.ParserIsEmpty() {
 		return
 	}
 
-	if (s.buffer[s.startIndex] == '\n') || ((s.buffer[s.startIndex] == '\r') && (s.buffer[s.startIndex+1] != '\n')) {
+	if s.buffer[s.startIndex] == '\n' && s.startIndex > 0 && s.buffer[s.startIndex-1] == '\r' && s.curColumn == 1 {
+		// the \r ended the line while it was the last byte of the buffer and
+		// the \n has been fed since (don't count \r\n twice)
+	} else if s.endsLine(s.startIndex) {
 		// we are progressing beyond a line boundary (don't count \r\n twice)
 		s.curLineNumber++
+		s.curColumn, s.curRuneColumn = 1, 1
+	} else {
+		s.curColumn++
+		// a character is counted at its lead byte, UTF-8 continuation bytes
+		// don't start a new one
+		if s.buffer[s.startIndex]&0xc0 != 0x80 {
+			s.curRuneColumn++
+		}
 	}
 	s.startIndex++
 }
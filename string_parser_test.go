@@ -0,0 +1,738 @@
+package commonutilities
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBytesParser(t *testing.T) {
+	packet := []byte(setupRequest)
+	s := NewBytes(packet)
+	if got := s.ConsumeWord(); string(got) != "SETUP" {
+		t.Errorf("ConsumeWord(%q) = %s", setupRequest, got)
+	}
+	s.ConsumeWhitespace()
+	url := s.ConsumeUntil(sURLStopConditions)
+	if string(url) != "rtsp://192.168.1.105:8554/test.264/track1" {
+		t.Errorf("ConsumeUntil(%q) = %s", setupRequest, url)
+	}
+	if &url[0] != &packet[6] {
+		t.Errorf("ConsumeUntil(%q) copied the buffer", setupRequest)
+	}
+	if got, ok := s.GetThru(' '); !ok || string(got) != "?channel=1&token=888888" {
+		t.Errorf("GetThru(%q) = %s, %v", setupRequest, got, ok)
+	}
+	if got, ok := s.GetThruEOL(); !ok || string(got) != "RTSP/1.0" {
+		t.Errorf("GetThruEOL(%q) = %s, %v", setupRequest, got, ok)
+	}
+	if got := s.GetCurrentLineNumber(); got != 2 {
+		t.Errorf("GetCurrentLineNumber(%q) = %d", setupRequest, got)
+	}
+	if got, ok := s.GetThru(':'); !ok || string(got) != "CSeq" {
+		t.Errorf("GetThru(%q) = %s, %v", setupRequest, got, ok)
+	}
+	s.ConsumeWhitespace()
+	if _, got := s.ConsumeInteger(); got != 3 {
+		t.Errorf("ConsumeInteger(%q) = %d", setupRequest, got)
+	}
+	if got := s.ConsumeEOL(); string(got) != "\r\n" {
+		t.Errorf("ConsumeEOL(%q) = %q", setupRequest, got)
+	}
+}
+
+func TestIncrementalParser(t *testing.T) {
+	for split := 0; split <= len(playRequest); split++ {
+		s := New(playRequest[:split])
+		s.SetIncremental(true)
+		fed := false
+		var tokens []string
+		steps := []func() bool{
+			func() bool { tokens = append(tokens, s.ConsumeWord()); return true },
+			func() bool { s.ConsumeWhitespace(); return true },
+			func() bool { tokens = append(tokens, s.ConsumeUntilWhitespace()); return true },
+			func() bool { s.ConsumeWhitespace(); return true },
+			func() bool { got, ok := s.GetThruEOL(); tokens = append(tokens, got); return ok },
+			func() bool { got, ok := s.GetThru(':'); tokens = append(tokens, got); return ok },
+			func() bool { s.ConsumeWhitespace(); return true },
+			func() bool { got, _ := s.ConsumeInteger(); tokens = append(tokens, got); return true },
+			func() bool { return s.ExpectEOL() },
+		}
+		for i := 0; i < len(steps); {
+			before := len(tokens)
+			ok := steps[i]()
+			if s.NeedMoreData() {
+				if fed {
+					t.Fatalf("split %d: step %d needs more data after the whole request was fed", split, i)
+				}
+				tokens = tokens[:before]
+				s.Feed(playRequest[split:])
+				fed = true
+				continue
+			}
+			if !ok {
+				t.Fatalf("split %d: step %d failed", split, i)
+			}
+			i++
+		}
+		want := []string{"PLAY", "rtsp://192.168.1.105:8554/test.264?channel=1&token=888888", "RTSP/1.0", "CSeq", "4"}
+		if len(tokens) != len(want) {
+			t.Fatalf("split %d: tokens = %q", split, tokens)
+		}
+		for i := range want {
+			if tokens[i] != want[i] {
+				t.Errorf("split %d: tokens = %q", split, tokens)
+				break
+			}
+		}
+		if got := s.GetCurrentLineNumber(); got != 3 {
+			t.Errorf("split %d: GetCurrentLineNumber() = %d", split, got)
+		}
+	}
+}
+
+func TestParserMark(t *testing.T) {
+	s := New("npt=10:07:00-\r\nsmpte=10:07:00-\nclock=19961108T142300Z-")
+	s.GetThruEOL()
+	mark := s.Mark()
+
+	// speculative npt parse of an smpte line
+	s.ConsumeWord()
+	if s.Expect('=') {
+		if _, ok := s.ConsumeNPTDuration(); ok {
+			s.GetThruEOL()
+		}
+	}
+	if got := s.GetCurrentLineNumber(); got != 3 {
+		t.Fatalf("GetCurrentLineNumber() = %d", got)
+	}
+	s.Reset(mark)
+	if got := s.ConsumeWord(); got != "smpte" || s.GetCurrentLineNumber() != 2 {
+		t.Errorf("ConsumeWord() after Reset = %q on line %d", got, s.GetCurrentLineNumber())
+	}
+
+	// backing up over \r\n, \n and \r alone must undo the line counting
+	var tests = []struct {
+		input string
+		back  int
+		line  int
+		rest  string
+	}{
+		{"a\r\nb\nc", 1, 3, "c"},
+		{"a\r\nb\nc", 2, 2, "\nc"},
+		{"a\r\nb\nc", 4, 1, "\nb\nc"},
+		{"a\r\nb\nc", 5, 1, "\r\nb\nc"},
+		{"a\rb\rc", 3, 2, "b\rc"},
+		{"a\rb\rc", 10, 1, "a\rb\rc"},
+	}
+	for _, test := range tests {
+		s := New(test.input)
+		s.ConsumeLength(len(test.input))
+		if got := s.ConsumeLength(-test.back); got != "" {
+			t.Errorf("ConsumeLength(%d) = %q", -test.back, got)
+		}
+		if s.GetCurrentLineNumber() != test.line || s.ConsumeLength(len(test.input)) != test.rest {
+			t.Errorf("ConsumeLength(%d) on %q: line %d", -test.back, test.input, s.GetCurrentLineNumber())
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	s := New("PLAY rtsp://a/b RTSP/1.0\r\nCSeq: four\r\n\r\n")
+	s.GetThruEOL()
+	if name, err := s.GetThruErr(':'); name != "CSeq" || err != nil {
+		t.Fatalf("GetThruErr(':') = %q, %v", name, err)
+	}
+	s.ConsumeWhitespace()
+	_, _, err := s.ConsumeIntegerErr()
+	var parseError *ParseError
+	if !errors.As(err, &parseError) {
+		t.Fatalf("ConsumeIntegerErr() error %v", err)
+	}
+	if parseError.Offset != 32 || parseError.Line != 2 || parseError.Column != 7 || parseError.Expected != "integer" || parseError.Snippet != "CSeq: four" {
+		t.Errorf("ConsumeIntegerErr() error %+v", parseError)
+	}
+	if want := `line 2, column 7: expected integer near "CSeq: four"`; err.Error() != want {
+		t.Errorf("Error() = %s, want %s", err, want)
+	}
+	if s.GetDataParsedLen() != 32 {
+		t.Errorf("ConsumeIntegerErr() consumed %q", s.GetStream()[26:s.GetDataParsedLen()])
+	}
+
+	var tests = []struct {
+		call func(s *StringParser) error
+		want string
+	}{
+		{func(s *StringParser) error { _, err := s.ConsumeWordErr(); return err }, "word"},
+		{func(s *StringParser) error { _, err := s.ConsumeUntilStopErr(';'); return err }, "';'"},
+		{func(s *StringParser) error { _, err := s.ConsumeLengthErr(20); return err }, "20 bytes"},
+		{func(s *StringParser) error { _, err := s.ConsumeFloatErr(); return err }, "number"},
+		{func(s *StringParser) error { _, err := s.ConsumeNPTErr(); return err }, "npt time"},
+		{func(s *StringParser) error { _, err := s.ConsumeNPTDurationErr(); return err }, "npt time"},
+		{func(s *StringParser) error { _, err := s.ConsumeEOLErr(); return err }, "end of line"},
+		{func(s *StringParser) error { return s.ExpectErr('=') }, "'='"},
+		{func(s *StringParser) error { return s.ExpectEOLErr() }, "end of line"},
+		{func(s *StringParser) error { _, err := s.GetThruEOLErr(); return err }, "end of line"},
+	}
+	for i, test := range tests {
+		s := New("  ?x-  ")
+		s.ConsumeWhitespace()
+		err := test.call(s)
+		if !errors.As(err, &parseError) || parseError.Expected != test.want || parseError.Column != 3 || s.GetDataParsedLen() != 2 {
+			t.Errorf("test %d: error %v", i, err)
+		}
+	}
+
+	s = New("npt=")
+	s.SetIncremental(true)
+	s.GetThru('=')
+	if _, err := s.ConsumeNPTDurationErr(); err != ErrNeedMoreData {
+		t.Errorf("ConsumeNPTDurationErr() error %v", err)
+	}
+}
+
+func TestParserColumn(t *testing.T) {
+	s := New("v=0\r\ns=摄像头 1\rm=video 0 RTP/AVP 96\n")
+	var tests = []struct {
+		step       func()
+		line       int
+		column     int
+		runeColumn int
+	}{
+		{func() {}, 1, 1, 1},
+		{func() { s.ConsumeLength(2) }, 1, 3, 3},
+		{func() { s.ConsumeUntil(sEOLMask) }, 1, 4, 4},
+		{func() { s.ConsumeLength(1) }, 1, 5, 5}, // between \r and \n
+		{func() { s.ExpectEOL() }, 2, 1, 1},
+		{func() { s.GetThru('=') }, 2, 3, 3},
+		{func() { s.ConsumeUntilWhitespace() }, 2, 12, 6},
+		{func() { s.ConsumeLength(-4) }, 2, 8, 5},
+		{func() { s.ConsumeLength(-1) }, 2, 7, 5}, // inside a character, counted at its lead byte
+		{func() { s.GetThruEOL() }, 3, 1, 1},
+		{func() { s.ConsumeLength(-1) }, 2, 14, 8},
+		{func() { s.ConsumeLength(-14) }, 1, 5, 5},
+		{func() { s.GetThruEOL(); s.GetThruEOL(); s.ConsumeWord() }, 3, 2, 2},
+	}
+	for i, test := range tests {
+		test.step()
+		if s.GetCurrentLineNumber() != test.line || s.GetCurrentColumn() != test.column || s.GetCurrentRuneColumn() != test.runeColumn {
+			t.Errorf("step %d: line %d, column %d, rune column %d, want %d, %d, %d", i, s.GetCurrentLineNumber(),
+				s.GetCurrentColumn(), s.GetCurrentRuneColumn(), test.line, test.column, test.runeColumn)
+		}
+	}
+
+	mark := s.Mark()
+	s.GetThruEOL()
+	s.Reset(mark)
+	if s.GetCurrentLineNumber() != 3 || s.GetCurrentColumn() != 2 {
+		t.Errorf("Reset() to line %d, column %d", s.GetCurrentLineNumber(), s.GetCurrentColumn())
+	}
+
+	s = New("s=摄像头 x\r\n")
+	s.GetThru(' ')
+	if err := s.ExpectEOLErr(); err.(*ParseError).Column != 13 || err.(*ParseError).RuneColumn != 7 {
+		t.Errorf("ExpectEOLErr() = %+v", err)
+	}
+
+	// a \r at the end of the buffer whose \n comes with the next Feed
+	for _, step := range []func(s *StringParser){
+		func(s *StringParser) { s.ConsumeLength(3) },
+		func(s *StringParser) { s.ConsumeLength(2); s.Expect('\r') },
+		func(s *StringParser) { s.GetThru('\r') },
+		func(s *StringParser) { s.ConsumeLength(2); s.ConsumeRune() },
+	} {
+		s := New("ab\r")
+		s.SetIncremental(true)
+		step(s)
+		s.Feed("\ncd")
+		if !s.ExpectEOL() || s.GetCurrentLineNumber() != 2 || s.GetCurrentColumn() != 1 {
+			t.Errorf("split \\r\\n: line %d, column %d", s.GetCurrentLineNumber(), s.GetCurrentColumn())
+		}
+		s.ConsumeLength(-1)
+		whole := New("ab\r\ncd")
+		whole.ConsumeLength(3)
+		if s.GetCurrentLineNumber() != whole.GetCurrentLineNumber() || s.GetCurrentColumn() != whole.GetCurrentColumn() {
+			t.Errorf("split \\r\\n backed up to line %d, column %d", s.GetCurrentLineNumber(), s.GetCurrentColumn())
+		}
+	}
+
+	// a character split across Feed calls is counted once
+	const line = "x摄y"
+	for split := 1; split < len(line); split++ {
+		s := NewBytes([]byte(line[:split]))
+		s.SetIncremental(true)
+		s.ConsumeLength(split)
+		s.Feed([]byte(line[split:]))
+		s.ConsumeLength(4 - split)
+		if got := s.GetCurrentRuneColumn(); got != 3 {
+			t.Errorf("split %d: GetCurrentRuneColumn() = %d", split, got)
+		}
+		if s.ConsumeLength(-4); s.GetCurrentRuneColumn() != 1 {
+			t.Errorf("split %d: GetCurrentRuneColumn() after backing up = %d", split, s.GetCurrentRuneColumn())
+		}
+	}
+}
+
+func TestUnicodeParser(t *testing.T) {
+	s := New("DESCRIBE rtsp://192.168.1.105/摄像头_前门，café/track1 RTSP/1.0")
+	s.GetThru('/')
+	s.GetThru('/')
+	s.GetThru('/')
+	if got := s.ConsumeWord(); got != "" {
+		t.Errorf("ConsumeWord() = %q", got)
+	}
+	if got := s.ConsumeWordUnicode(); got != "摄像头_前门" || s.GetCurrentRuneColumn() != 37 {
+		t.Errorf("ConsumeWordUnicode() = %q, rune column %d", got, s.GetCurrentRuneColumn())
+	}
+	if r, size := s.PeekRune(); r != '，' || size != 3 {
+		t.Errorf("PeekRune() = %q, %d", r, size)
+	}
+	if r, size := s.ConsumeRune(); r != '，' || size != 3 {
+		t.Errorf("ConsumeRune() = %q, %d", r, size)
+	}
+	if got := s.ConsumeUntilRune(MaskOf("/").Runes()); got != "café" {
+		t.Errorf("ConsumeUntilRune() = %q", got)
+	}
+
+	b := NewBytes([]byte("é\xff"))
+	if r, size := b.ConsumeRune(); r != 'é' || size != 2 {
+		t.Errorf("ConsumeRune() = %q, %d", r, size)
+	}
+	if r, size := b.ConsumeRune(); r != utf8.RuneError || size != 1 {
+		t.Errorf("ConsumeRune() = %q, %d", r, size)
+	}
+	if r, size := b.PeekRune(); r != utf8.RuneError || size != 0 {
+		t.Errorf("PeekRune() = %q, %d", r, size)
+	}
+
+	// a character split between two packets
+	name := "前门 "
+	s = New(name[:4])
+	s.SetIncremental(true)
+	if got := s.ConsumeWordUnicode(); got != "" || !s.NeedMoreData() {
+		t.Errorf("ConsumeWordUnicode() = %q before the end of the character", got)
+	}
+	s.Feed(name[4:])
+	if got := s.ConsumeWordUnicode(); got != "前门" {
+		t.Errorf("ConsumeWordUnicode() = %q", got)
+	}
+	s = New(name[:2])
+	s.SetIncremental(true)
+	if r, _ := s.ConsumeRune(); r != utf8.RuneError || !s.NeedMoreData() {
+		t.Errorf("ConsumeRune() = %q before the end of the character", r)
+	}
+}
+
+func TestConsumeIntegers(t *testing.T) {
+	var tests = []struct {
+		input     string
+		maxDigits int
+		// the values in decimal, "" for an error
+		uint64 string
+		int64  string
+		hex    string
+		rest   string
+	}{
+		{"4\r\n", 0, "4", "4", "4", "\r\n"},
+		{"0000000042;", 0, "42", "42", "66", ";"},
+		{"18446744073709551615", 0, "18446744073709551615", "", "", "18446744073709551615"},
+		{"9223372036854775807", 0, "9223372036854775807", "9223372036854775807", "", "9223372036854775807"},
+		{"abcd1234 ", 8, "", "", "2882343476", " "},
+		{"ABCD1234", 0, "", "", "2882343476", ""},
+		{"ffffffffffffffff", 0, "", "", "18446744073709551615", ""},
+		{"1234567890", 9, "", "", "", "1234567890"},
+	}
+	format := func(value any, err error) string {
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprint(value)
+	}
+	for _, test := range tests {
+		s := New(test.input)
+		s.SetMaxDigits(test.maxDigits)
+		mark := s.Mark()
+		if got, err := s.ConsumeUint64(); format(got, err) != test.uint64 {
+			t.Errorf("ConsumeUint64(%q) = %d, %v", test.input, got, err)
+		}
+		s.Reset(mark)
+		if got, err := s.ConsumeInt64(); format(got, err) != test.int64 {
+			t.Errorf("ConsumeInt64(%q) = %d, %v", test.input, got, err)
+		}
+		s.Reset(mark)
+		if got, err := s.ConsumeHex(); format(got, err) != test.hex {
+			t.Errorf("ConsumeHex(%q) = %x, %v", test.input, got, err)
+		}
+		if rest := s.ConsumeLength(s.GetDataRemaining()); rest != test.rest {
+			t.Errorf("ConsumeHex(%q) left %q", test.input, rest)
+		}
+	}
+
+	for _, input := range []string{"-9223372036854775808", "+17", "-0", "-17;"} {
+		want, _ := strconv.ParseInt(strings.TrimSuffix(input, ";"), 10, 64)
+		if got, err := New(input).ConsumeInt64(); got != want || err != nil {
+			t.Errorf("ConsumeInt64(%q) = %d, %v", input, got, err)
+		}
+	}
+
+	const signed = "-123 "
+	for split := 0; split < len(signed); split++ {
+		s := New(signed[:split])
+		s.SetIncremental(true)
+		if _, err := s.ConsumeInt64(); err != ErrNeedMoreData {
+			t.Errorf("split %d: ConsumeInt64(%q) error %v", split, signed[:split], err)
+		}
+		s.Feed(signed[split:])
+		if got, err := s.ConsumeInt64(); got != -123 || err != nil {
+			t.Errorf("split %d: ConsumeInt64(%q) = %d, %v", split, signed, got, err)
+		}
+	}
+
+	for _, input := range []string{"18446744073709551616", "9223372036854775808", "-9223372036854775809", "-", "+x", "", "x"} {
+		s := New(input)
+		_, err := s.ConsumeInt64()
+		if err == nil || input != "" && s.GetDataParsedLen() != 0 {
+			t.Errorf("ConsumeInt64(%q) error %v, consumed %d", input, err, s.GetDataParsedLen())
+		}
+		if isRange := errors.Is(err, strconv.ErrRange); isRange != (len(input) > 2) {
+			t.Errorf("ConsumeInt64(%q) error %v", input, err)
+		}
+	}
+
+	if _, _, err := New("4294967296").ConsumeIntegerErr(); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("ConsumeIntegerErr(4294967296) error %v", err)
+	}
+	if digits, value, err := New("4294967295").ConsumeIntegerErr(); digits != "4294967295" || value != math.MaxUint32 || err != nil {
+		t.Errorf("ConsumeIntegerErr(4294967295) = %q, %d, %v", digits, value, err)
+	}
+
+	s := New("CSeq: 12")
+	s.SetIncremental(true)
+	s.GetThru(':')
+	s.ConsumeWhitespace()
+	if _, err := s.ConsumeUint64(); err != ErrNeedMoreData {
+		t.Errorf("ConsumeUint64() error %v", err)
+	}
+	s.Feed("3\r\n")
+	if got, err := s.ConsumeUint64(); got != 123 || err != nil {
+		t.Errorf("ConsumeUint64() = %d, %v", got, err)
+	}
+}
+
+func TestConsumeFloat(t *testing.T) {
+	var tests = []struct {
+		input string
+		rest  string
+	}{
+		{"1.5", ""},
+		{"-0.5;", ";"},
+		{"+2", ""},
+		{".25 ", " "},
+		{"5.", ""},
+		{"29.97\r\n", "\r\n"},
+		{"0.1", ""},
+		{"0.30000000000000004", ""},
+		{"2.5e-3x", "x"},
+		{"1E+10", ""},
+		{"1e", "e"},
+		{"1e-", "e-"},
+		{"123456789012345678901234567890", ""},
+		{"1.7976931348623157e308", ""},
+		{"4.9e-324", ""},
+	}
+	for _, test := range tests {
+		s := New(test.input)
+		want, _ := strconv.ParseFloat(strings.TrimSuffix(test.input, test.rest), 64)
+		if got := s.ConsumeFloat(); got != want {
+			t.Errorf("ConsumeFloat(%q) = %v, want %v", test.input, got, want)
+		}
+		if rest := s.ConsumeLength(s.GetDataRemaining()); rest != test.rest {
+			t.Errorf("ConsumeFloat(%q) left %q", test.input, rest)
+		}
+	}
+
+	for _, input := range []string{"", ".", "-", "+.e5", "e5", "x1"} {
+		s := New(input)
+		if got := s.ConsumeFloat(); got != 0 || (input != "" && s.GetDataParsedLen() != 0) {
+			t.Errorf("ConsumeFloat(%q) = %v", input, got)
+		}
+		if _, err := New(input).ConsumeFloatErr(); err == nil {
+			t.Errorf("ConsumeFloatErr(%q) succeeded", input)
+		}
+	}
+
+	s := New("1e400")
+	if _, err := s.ConsumeFloatErr(); !errors.Is(err, strconv.ErrRange) || s.GetDataParsedLen() != 0 {
+		t.Errorf("ConsumeFloatErr(1e400) error %v", err)
+	}
+	if got := s.ConsumeFloat(); !math.IsInf(got, 1) {
+		t.Errorf("ConsumeFloat(1e400) = %v", got)
+	}
+
+	for _, split := range []int{1, 2, 3, 4} {
+		s := New("Scale: -1.5e1\r\n"[:7+split])
+		s.SetIncremental(true)
+		s.GetThru(':')
+		s.ConsumeWhitespace()
+		if _, err := s.ConsumeFloatErr(); err != ErrNeedMoreData {
+			t.Errorf("split %d: ConsumeFloatErr() error %v", split, err)
+		}
+		s.Feed("Scale: -1.5e1\r\n"[7+split:])
+		if got, err := s.ConsumeFloatErr(); got != -15 || err != nil {
+			t.Errorf("split %d: ConsumeFloatErr() = %v, %v", split, got, err)
+		}
+	}
+}
+
+func TestConsumeQuotedString(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  string
+		rest  string
+	}{
+		{`"abc"`, "abc", ""},
+		{`""; x`, "", "; x"},
+		{`"a;b,c" d`, "a;b,c", " d"},
+		{`"say \"hi\""`, `say "hi"`, ""},
+		{`"C:\\temp\x"`, `C:\tempx`, ""},
+		{`"ü\ü"`, "üü", ""},
+	}
+	for _, test := range tests {
+		s := New(test.input)
+		got, err := s.ConsumeQuotedString()
+		if got != test.want || err != nil {
+			t.Errorf("ConsumeQuotedString(%q) = %q, %v, want %q", test.input, got, err, test.want)
+		}
+		if rest := s.ConsumeLength(s.GetDataRemaining()); rest != test.rest {
+			t.Errorf("ConsumeQuotedString(%q) left %q", test.input, rest)
+		}
+		if got, err := NewBytes([]byte(test.input)).ConsumeQuotedString(); string(got) != test.want || err != nil {
+			t.Errorf("BytesParser.ConsumeQuotedString(%q) = %q, %v", test.input, got, err)
+		}
+	}
+
+	for _, input := range []string{"", "abc", `'abc'`, `"abc`, `"abc\"`, `"abc\`} {
+		s := New(input)
+		var parseError *ParseError
+		if got, err := s.ConsumeQuotedString(); !errors.As(err, &parseError) || got != "" || (input != "" && s.GetDataParsedLen() != 0) {
+			t.Errorf("ConsumeQuotedString(%q) = %q, %v", input, got, err)
+		}
+	}
+	if _, err := New(`"abc`).ConsumeQuotedString(); err.Error() != `line 1, column 1: expected closing '"' near "\"abc"` {
+		t.Errorf("ConsumeQuotedString() error %v", err)
+	}
+
+	const input = `realm="a \"b\" c", x`
+	for split := 7; split < 17; split++ {
+		s := New(input[:split])
+		s.SetIncremental(true)
+		s.GetThru('=')
+		if _, err := s.ConsumeQuotedString(); err != ErrNeedMoreData || s.GetDataParsedLen() != 6 {
+			t.Errorf("split %d: ConsumeQuotedString() error %v", split, err)
+		}
+		s.Feed(input[split:])
+		if got, err := s.ConsumeQuotedString(); got != `a "b" c` || err != nil {
+			t.Errorf("split %d: ConsumeQuotedString() = %q, %v", split, got, err)
+		}
+	}
+
+	var unquoteTests = []struct {
+		input string
+		want  string
+	}{
+		{`"abc"`, "abc"},
+		{`'abc'`, "abc"},
+		{`"a\"b"`, `a"b`},
+		{`'a\'`, `a\`},
+		{`'abc"`, `'abc"`},
+		{`"abc'`, `"abc'`},
+		{`"abc\"`, `"abc\"`},
+		{`"a"b"`, `"a"b"`},
+		{`"`, `"`},
+		{"abc", "abc"},
+	}
+	for _, test := range unquoteTests {
+		if got := New("").UnQuote(test.input); got != test.want {
+			t.Errorf("UnQuote(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestExpectString(t *testing.T) {
+	var tests = []struct {
+		input  string
+		prefix string
+		expect bool
+		fold   bool
+	}{
+		{"RTSP/1.0 200 OK", "RTSP/", true, true},
+		{"rtsp/1.0", "RTSP/", false, true},
+		{"RTSP://a/b", "rtsp://", false, true},
+		{"HTTP/1.1", "RTSP/", false, false},
+		{"RTS", "RTSP/", false, false},
+		{"", "RTSP/", false, false},
+		{"x", "", true, true},
+	}
+	for _, test := range tests {
+		if got := New(test.input).HasPrefix(test.prefix); got != test.expect {
+			t.Errorf("HasPrefix(%q, %q) = %v", test.input, test.prefix, got)
+		}
+		if got := New(test.input).HasPrefixFold(test.prefix); got != test.fold {
+			t.Errorf("HasPrefixFold(%q, %q) = %v", test.input, test.prefix, got)
+		}
+		s := New(test.input)
+		if got := s.ExpectString(test.prefix); got != test.expect {
+			t.Errorf("ExpectString(%q, %q) = %v", test.input, test.prefix, got)
+		}
+		if want := len(test.prefix); !test.expect && test.input != "" && s.GetDataParsedLen() != 0 ||
+			test.expect && s.GetDataParsedLen() != want {
+			t.Errorf("ExpectString(%q, %q) consumed %d bytes", test.input, test.prefix, s.GetDataParsedLen())
+		}
+		s = New(test.input)
+		if got := s.ExpectFold(test.prefix); got != test.fold {
+			t.Errorf("ExpectFold(%q, %q) = %v", test.input, test.prefix, got)
+		}
+		if want := len(test.prefix); !test.fold && test.input != "" && s.GetDataParsedLen() != 0 ||
+			test.fold && s.GetDataParsedLen() != want {
+			t.Errorf("ExpectFold(%q, %q) consumed %d bytes", test.input, test.prefix, s.GetDataParsedLen())
+		}
+	}
+
+	for _, url := range []string{"rtsp://192.168.1.105:8554/test.264", "RTSP://192.168.1.105:8554/test.264", "Rtsp://192.168.1.105:8554/test.264"} {
+		s := New(url)
+		if !s.ExpectFold("rtsp://") || s.ConsumeLength(s.GetDataRemaining()) != "192.168.1.105:8554/test.264" {
+			t.Errorf("ExpectFold(%q, %q) failed", url, "rtsp://")
+		}
+	}
+
+	s := NewBytes([]byte("Content-Length: 12\r\n"))
+	if got := string(s.PeekN(7)); got != "Content" || s.GetDataParsedLen() != 0 {
+		t.Errorf("PeekN(7) = %q", got)
+	}
+	if !s.ExpectFold("content-length:") || s.GetCurrentColumn() != 16 || string(s.PeekN(100)) != " 12\r\n" {
+		t.Errorf("ExpectFold() left %q at column %d", s.PeekN(100), s.GetCurrentColumn())
+	}
+
+	for split := 0; split < 5; split++ {
+		s := New("RTSP/1.0"[:split])
+		s.SetIncremental(true)
+		if s.ExpectString("RTSP/") {
+			t.Errorf("split %d: ExpectString() succeeded", split)
+		}
+		s.Feed("RTSP/1.0"[split:])
+		if !s.ExpectString("RTSP/") || s.PeekN(10) != "1.0" {
+			t.Errorf("split %d: ExpectString() failed", split)
+		}
+	}
+	s2 := New("RTX/1.0")
+	s2.SetIncremental(true)
+	if s2.ExpectString("RTSP/") || s2.ExpectString("RTSP/") {
+		t.Errorf("ExpectString(RTX/1.0) succeeded")
+	}
+}
+
+func TestScan(t *testing.T) {
+	s := New("DESCRIBE rtsp://192.168.1.105:8554/test.264 RTSP/1.0\r\nCSeq: 2\r\n")
+	var method, url string
+	var major, minor int
+	var cseq uint32
+	if err := s.Scan("%w %u RTSP/%d.%d%eol", &method, &url, &major, &minor); err != nil {
+		t.Fatalf("Scan() error %v", err)
+	}
+	if method != "DESCRIBE" || url != "rtsp://192.168.1.105:8554/test.264" || major != 1 || minor != 0 {
+		t.Errorf("Scan() = %q, %q, %d, %d", method, url, major, minor)
+	}
+	if err := s.Scan("CSeq: %d%eol", &cseq); err != nil || cseq != 2 || !s.ParserIsEmpty() {
+		t.Errorf("Scan() = %d, %v", cseq, err)
+	}
+
+	var name, rest string
+	var ssrc uint32
+	var scale float64
+	var token []byte
+	b := NewBytes([]byte(`x-name="a \"b\""	ssrc=0A0B0C0D 100% scale=-1.5e1 tail text`))
+	if err := b.Scan("%w=%q ssrc=%x 100%% scale=%f %w %s", &name, &rest, &ssrc, &scale, &token, &rest); err != nil {
+		t.Fatalf("Scan() error %v", err)
+	}
+	if name != "x-name" || ssrc != 0x0A0B0C0D || scale != -15 || string(token) != "tail" || rest != "text" {
+		t.Errorf("Scan() = %q, %x, %v, %q, %q", name, ssrc, scale, token, rest)
+	}
+
+	var tests = []struct {
+		input   string
+		pattern string
+		want    string
+	}{
+		{"RTSP/1.0 200 OK", "HTTP/%d.%d", `line 1, column 1: expected "HTTP/" near "RTSP/1.0 200 OK"`},
+		{"RTSP/1.0  200", "RTSP/%d.%d %d %w", `line 1, column 14: expected whitespace near "RTSP/1.0  200"`},
+		{"RTSP/1.0200", "RTSP/%d.%d %d", `line 1, column 12: expected whitespace near "RTSP/1.0200"`},
+		{"RTSP/1.x", "RTSP/%d.%d", `line 1, column 8: expected integer near "RTSP/1.x"`},
+		{"t=0 4294967296", "t=%d %d", `line 1, column 5: integer overflows uint32: value out of range near "t=0 4294967296"`},
+		{"t=-1 0", "t=%d %d", `line 1, column 3: integer overflows uint32: value out of range near "t=-1 0"`},
+		{"a b", "%w%eol", `line 1, column 2: expected end of line near "a b"`},
+	}
+	for _, test := range tests {
+		s := New(test.input)
+		var dst []any
+		for _, verb := range strings.Split(test.pattern, "%")[1:] {
+			var number uint32
+			var word string
+			switch verb[0] {
+			case 'd':
+				dst = append(dst, &number)
+			case 'w':
+				dst = append(dst, &word)
+			}
+		}
+		if err := s.Scan(test.pattern, dst...); err == nil || err.Error() != test.want {
+			t.Errorf("Scan(%q, %q) error %v", test.input, test.pattern, err)
+		}
+		if s.GetDataParsedLen() != 0 {
+			t.Errorf("Scan(%q, %q) consumed %d bytes", test.input, test.pattern, s.GetDataParsedLen())
+		}
+	}
+	if err := New("t=0 4294967296").Scan("t=%d %d", &cseq, &cseq); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("Scan() error %v", err)
+	}
+
+	for _, pattern := range []string{"%w", "%w %w %w", "%z", "%"} {
+		var word string
+		if err := New("a b").Scan(pattern, &word, &word); err == nil {
+			t.Errorf("Scan(%q) succeeded", pattern)
+		}
+	}
+	if err := New("a").Scan("%d", &name); err == nil {
+		t.Errorf("Scan(%%d, *string) succeeded")
+	}
+	var nilTests = []struct {
+		pattern string
+		dst     any
+	}{
+		{"%w", (*string)(nil)},
+		{"%u", (*[]byte)(nil)},
+		{"%d", (*int)(nil)},
+		{"%f", (*float64)(nil)},
+		{"%f", (*float32)(nil)},
+	}
+	for _, test := range nilTests {
+		if err := New("1").Scan(test.pattern, test.dst); err == nil {
+			t.Errorf("Scan(%q, %T) succeeded", test.pattern, test.dst)
+		}
+	}
+
+	for split := 0; split < 12; split++ {
+		s := New("PLAY * RTSP/1.0\r\n"[:split])
+		s.SetIncremental(true)
+		if err := s.Scan("%w %u %s%eol", &method, &url, &rest); err != ErrNeedMoreData {
+			t.Errorf("split %d: Scan() error %v", split, err)
+		}
+		s.Feed("PLAY * RTSP/1.0\r\n"[split:])
+		if err := s.Scan("%w %u %s%eol", &method, &url, &rest); err != nil || rest != "RTSP/1.0" {
+			t.Errorf("split %d: Scan() = %q, %v", split, rest, err)
+		}
+	}
+}
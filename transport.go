@@ -0,0 +1,230 @@
+package commonutilities
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PortRange is a "first-last" pair such as client_port=37175-37176 or
+// interleaved=0-1. Last is zero when only one number was given.
+type PortRange struct {
+	First int
+	Last  int
+}
+
+func (p PortRange) String() string {
+	if p.Last == 0 {
+		return strconv.Itoa(p.First)
+	}
+	return strconv.Itoa(p.First) + "-" + strconv.Itoa(p.Last)
+}
+
+// Delivery is the unicast or multicast parameter of a transport-spec.
+type Delivery int
+
+const (
+	// DeliveryDefault means the transport-spec named neither, which RFC
+	// 2326 section 12.39 reads as multicast
+	DeliveryDefault Delivery = iota
+	DeliveryUnicast
+	DeliveryMulticast
+)
+
+// Transport is one transport-spec of a Transport header, RFC 2326 section 12.39.
+type Transport struct {
+	// Profile is the transport protocol, profile and lower transport,
+	// e.g. RTP/AVP, RTP/AVP/TCP or RTP/SAVP
+	Profile     string
+	Delivery    Delivery
+	Destination string
+	Source      string
+	Interleaved *PortRange
+	Append      bool
+	TTL         int
+	Layers      int
+	Port        *PortRange
+	ClientPort  *PortRange
+	ServerPort  *PortRange
+	SSRC        uint32
+	HasSSRC     bool
+	Mode        string
+	// Extensions keeps parameters this package does not know about as they
+	// were sent, e.g. x-dynamic-rate=1
+	Extensions []HeaderParam
+}
+
+// ParseTransport parses a Transport header into its comma separated
+// alternatives, in the client's order of preference.
+func ParseTransport(value string) ([]Transport, error) {
+	elements, err := ParseHeaderValue(value)
+	if err != nil {
+		return nil, fmt.Errorf("malformed Transport %q: %w", value, err)
+	}
+	transports := make([]Transport, 0, len(elements))
+	for _, element := range elements {
+		transport, err := parseTransportSpec(element)
+		if err != nil {
+			return nil, err
+		}
+		transports = append(transports, transport)
+	}
+	if len(transports) == 0 {
+		return nil, fmt.Errorf("empty Transport header")
+	}
+	return transports, nil
+}
+
+func parseTransportSpec(spec HeaderElement) (transport Transport, err error) {
+	transport.Profile = spec.Token
+	if !strings.HasPrefix(transport.Profile, "RTP/") {
+		return transport, fmt.Errorf("unsupported transport %q", transport.Profile)
+	}
+
+	for _, param := range spec.Params {
+		value := param.Value
+		switch param.Name {
+		case "unicast":
+			transport.Delivery = DeliveryUnicast
+		case "multicast":
+			transport.Delivery = DeliveryMulticast
+		case "append":
+			transport.Append = true
+		case "destination":
+			transport.Destination = value
+		case "source":
+			transport.Source = value
+		case "mode":
+			transport.Mode = value
+		case "interleaved":
+			transport.Interleaved, err = parsePortRange(value, maxChannel)
+		case "port":
+			transport.Port, err = parsePortRange(value, maxPort)
+		case "client_port":
+			transport.ClientPort, err = parsePortRange(value, maxPort)
+		case "server_port":
+			transport.ServerPort, err = parsePortRange(value, maxPort)
+		case "ttl":
+			transport.TTL, err = strconv.Atoi(value)
+		case "layers":
+			transport.Layers, err = strconv.Atoi(value)
+		case "ssrc":
+			transport.SSRC, transport.HasSSRC, err = parseSSRC(value)
+		default:
+			transport.Extensions = append(transport.Extensions, param)
+			continue
+		}
+		if err != nil || (param.HasValue && value == "") {
+			return transport, fmt.Errorf("malformed transport parameter %q", param.String())
+		}
+	}
+	return transport, nil
+}
+
+// parseSSRC reads the 8 hexadecimal digits of an ssrc parameter.
+func parseSSRC(value string) (uint32, bool, error) {
+	parser := New(value)
+	parser.SetMaxDigits(8)
+	ssrc, err := parser.ConsumeHex()
+	if err == nil && !parser.ParserIsEmpty() {
+		err = parser.NewParseError("end of ssrc")
+	}
+	return uint32(ssrc), true, err
+}
+
+// the largest port number and interleaved channel number
+const (
+	maxPort    = 65535
+	maxChannel = 255
+)
+
+// parsePortRange parses "first" or "first-last", each at most maxValue.
+func parsePortRange(value string, maxValue uint64) (*PortRange, error) {
+	parser := New(value)
+	parser.SetMaxDigits(5)
+	first, err := parser.ConsumeUint64()
+	if err != nil || first > maxValue {
+		return nil, fmt.Errorf("malformed range %q", value)
+	}
+	portRange := &PortRange{First: int(first)}
+	if parser.Expect('-') {
+		last, err := parser.ConsumeUint64()
+		if err != nil || last > maxValue {
+			return nil, fmt.Errorf("malformed range %q", value)
+		}
+		portRange.Last = int(last)
+	}
+	if !parser.ParserIsEmpty() {
+		return nil, fmt.Errorf("malformed range %q", value)
+	}
+	return portRange, nil
+}
+
+// Multicast reports whether the stream is multicast, the default when the
+// transport-spec does not say.
+func (t Transport) Multicast() bool {
+	return t.Delivery != DeliveryUnicast
+}
+
+// LowerTransport returns "TCP" or "UDP".
+func (t Transport) LowerTransport() string {
+	if strings.HasSuffix(t.Profile, "/TCP") {
+		return "TCP"
+	}
+	return "UDP"
+}
+
+// String formats the transport-spec for a Transport header.
+func (t Transport) String() string {
+	spec := HeaderElement{Token: t.Profile}
+	switch t.Delivery {
+	case DeliveryUnicast:
+		spec.AddFlag("unicast")
+	case DeliveryMulticast:
+		spec.AddFlag("multicast")
+	}
+	if t.Destination != "" {
+		spec.AddParam("destination", t.Destination)
+	}
+	if t.Source != "" {
+		spec.AddParam("source", t.Source)
+	}
+	if t.Interleaved != nil {
+		spec.AddParam("interleaved", t.Interleaved.String())
+	}
+	if t.Append {
+		spec.AddFlag("append")
+	}
+	if t.TTL != 0 {
+		spec.AddParam("ttl", strconv.Itoa(t.TTL))
+	}
+	if t.Layers != 0 {
+		spec.AddParam("layers", strconv.Itoa(t.Layers))
+	}
+	if t.Port != nil {
+		spec.AddParam("port", t.Port.String())
+	}
+	if t.ClientPort != nil {
+		spec.AddParam("client_port", t.ClientPort.String())
+	}
+	if t.ServerPort != nil {
+		spec.AddParam("server_port", t.ServerPort.String())
+	}
+	if t.HasSSRC {
+		spec.AddParam("ssrc", fmt.Sprintf("%08X", t.SSRC))
+	}
+	if t.Mode != "" {
+		spec.AddParam("mode", t.Mode)
+	}
+	spec.Params = append(spec.Params, t.Extensions...)
+	return spec.String()
+}
+
+// FormatTransport formats a Transport header from its alternatives.
+func FormatTransport(transports []Transport) string {
+	specs := make([]string, len(transports))
+	for i, transport := range transports {
+		specs[i] = transport.String()
+	}
+	return strings.Join(specs, ",")
+}